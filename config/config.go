@@ -0,0 +1,235 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"practical-task/units"
+)
+
+// 配置相关的环境变量，优先级高于配置文件
+const (
+	EnvConfig   = "DAPP_CONFIG"       // 配置文件路径
+	EnvNetwork  = "DAPP_NETWORK"      // 使用的网络配置名
+	EnvRPCURL   = "DAPP_RPC_URL"      // 覆盖网络配置的 RPC URL
+	EnvWSURL    = "DAPP_WS_URL"       // 覆盖网络配置的 WebSocket URL
+	EnvChainID  = "DAPP_CHAIN_ID"     // 覆盖网络配置的预期链 ID
+	EnvExplorer = "DAPP_EXPLORER_URL" // 覆盖网络配置的区块浏览器 URL
+	EnvAccount  = "DAPP_ACCOUNT"      // 使用的账户名
+)
+
+// DefaultPath 是未指定配置文件时在当前目录查找的文件名
+const DefaultPath = "config.json"
+
+// Config 是全部网络配置与账户配置
+type Config struct {
+	DefaultNetwork string              `json:"defaultNetwork"`
+	Networks       map[string]*Network `json:"networks"`
+	DefaultAccount string              `json:"defaultAccount"`
+	Accounts       map[string]string   `json:"accounts"` // 账户名 -> 密钥来源，如 "keystore:./keys/dev.json"
+}
+
+// Network 是一个命名的网络配置
+type Network struct {
+	Name        string      `json:"-"`
+	RPCURL      string      `json:"rpcUrl"`
+	WSURL       string      `json:"wsUrl"`
+	ChainID     uint64      `json:"chainId"` // 预期的 EIP-155 链 ID
+	ExplorerURL string      `json:"explorerUrl"`
+	Gas         GasSettings `json:"gas"`
+}
+
+// GasSettings 是网络的默认 Gas 设置，未设置的字段使用节点建议值。费用字段可以写成
+// wei 数值，也可以写成带单位的字符串，如 "2 gwei"
+type GasSettings struct {
+	Legacy    bool     `json:"legacy"`               // 默认发送 legacy 交易
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas"` // 固定的小费上限
+	GasFeeCap *big.Int `json:"maxFeePerGas"`         // 总费用上限（legacy 交易为 gasPrice）的最大值
+
+	LimitMultiplier float64 `json:"gasLimitMultiplier"` // Gas 估算值的安全系数，0 表示使用默认值
+	LimitCap        uint64  `json:"gasLimitCap"`        // Gas 限制的上限，0 表示不限制
+}
+
+// UnmarshalJSON 解析 Gas 设置，费用字段支持 wei 数值或带单位的字符串
+func (g *GasSettings) UnmarshalJSON(data []byte) error {
+	type plain GasSettings
+	var raw struct {
+		plain
+		GasTipCap json.RawMessage `json:"maxPriorityFeePerGas"`
+		GasFeeCap json.RawMessage `json:"maxFeePerGas"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*g = GasSettings(raw.plain)
+	var err error
+	if g.GasTipCap, err = parseFee("maxPriorityFeePerGas", raw.GasTipCap); err != nil {
+		return err
+	}
+	if g.GasFeeCap, err = parseFee("maxFeePerGas", raw.GasFeeCap); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseFee 解析费用字段，数值按 wei 处理，字符串可带单位（默认 wei）
+func parseFee(name string, raw json.RawMessage) (*big.Int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s = string(raw)
+	}
+	fee, err := units.ParseAmount(s, "wei")
+	if err != nil {
+		return nil, fmt.Errorf("gas.%s: %w", name, err)
+	}
+	return fee, nil
+}
+
+// Default 返回内置的默认配置，仅包含 Sepolia 网络且未设置 RPC URL
+func Default() *Config {
+	return &Config{
+		DefaultNetwork: "sepolia",
+		Networks: map[string]*Network{
+			"sepolia": {
+				ChainID:     11155111,
+				ExplorerURL: "https://sepolia.etherscan.io",
+			},
+		},
+	}
+}
+
+// Load 读取配置文件。path 为空时依次尝试环境变量 DAPP_CONFIG 与当前目录下的 config.json，
+// 都不存在时使用内置默认配置
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path, explicit = os.LookupEnv(EnvConfig)
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// LoadNetwork 读取配置文件并返回选定的网络配置，参见 Load 与 Config.Network
+func LoadNetwork(path, name string) (*Config, *Network, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	network, err := cfg.Network(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, network, nil
+}
+
+// Network 返回指定名称的网络配置，并应用环境变量覆盖。name 为空时依次使用
+// 环境变量 DAPP_NETWORK 与配置中的 defaultNetwork
+func (c *Config) Network(name string) (*Network, error) {
+	if name == "" {
+		name = os.Getenv(EnvNetwork)
+	}
+	if name == "" {
+		name = c.DefaultNetwork
+	}
+	if name == "" {
+		return nil, errors.New("未指定网络，请在配置文件中设置 defaultNetwork 或使用 -network 参数")
+	}
+	profile, ok := c.Networks[name]
+	if !ok {
+		return nil, fmt.Errorf("配置中不存在网络 %q", name)
+	}
+	network := *profile
+	network.Name = name
+
+	if v := os.Getenv(EnvRPCURL); v != "" {
+		network.RPCURL = v
+	}
+	if v := os.Getenv(EnvWSURL); v != "" {
+		network.WSURL = v
+	}
+	if v := os.Getenv(EnvExplorer); v != "" {
+		network.ExplorerURL = v
+	}
+	if v := os.Getenv(EnvChainID); v != "" {
+		chainID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("环境变量 %s 的值 %q 不是有效的链ID", EnvChainID, v)
+		}
+		network.ChainID = chainID
+	}
+	return &network, nil
+}
+
+// Endpoint 返回网络的 RPC URL，未配置时返回错误
+func (n *Network) Endpoint() (string, error) {
+	if n.RPCURL == "" {
+		return "", fmt.Errorf("网络 %q 未配置 rpcUrl，请在配置文件中设置或设置环境变量 %s", n.Name, EnvRPCURL)
+	}
+	return n.RPCURL, nil
+}
+
+// Account 返回指定账户的密钥来源。name 为空时依次使用环境变量 DAPP_ACCOUNT
+// 与配置中的 defaultAccount；都未设置时返回空字符串，由调用方使用默认来源
+func (c *Config) Account(name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(EnvAccount)
+	}
+	if name == "" {
+		name = c.DefaultAccount
+	}
+	if name == "" {
+		return "", nil
+	}
+	spec, ok := c.Accounts[name]
+	if !ok {
+		return "", fmt.Errorf("配置中不存在账户 %q", name)
+	}
+	return spec, nil
+}
+
+// ExpectedChainID 返回预期的链 ID，未配置时返回 nil
+func (n *Network) ExpectedChainID() *big.Int {
+	if n.ChainID == 0 {
+		return nil
+	}
+	return new(big.Int).SetUint64(n.ChainID)
+}
+
+// TxURL 返回交易在区块浏览器中的链接，未配置浏览器时返回空字符串
+func (n *Network) TxURL(hash common.Hash) string {
+	if n.ExplorerURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(n.ExplorerURL, "/") + "/tx/" + hash.Hex()
+}
+
+// AddressURL 返回地址在区块浏览器中的链接，未配置浏览器时返回空字符串
+func (n *Network) AddressURL(addr common.Address) string {
+	if n.ExplorerURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(n.ExplorerURL, "/") + "/address/" + addr.Hex()
+}
@@ -0,0 +1,140 @@
+package keysource
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultSpec 是未指定密钥来源时使用的来源：从环境变量 ETH_PRIVATE_KEY 读取十六进制私钥
+const DefaultSpec = "env:ETH_PRIVATE_KEY"
+
+// PassphraseEnv 是 keystore 密码的环境变量，设置后不再交互式提示输入密码
+const PassphraseEnv = "ETH_KEYSTORE_PASSWORD"
+
+// Key 是加载得到的签名私钥及其地址
+type Key struct {
+	PrivateKey *ecdsa.PrivateKey
+	Address    common.Address
+	Source     string // 密钥来源的描述，不包含任何密钥内容
+}
+
+// Load 按来源描述加载私钥，支持以下形式：
+//
+//	keystore:<路径>  go-ethereum keystore JSON 文件，密码从 ETH_KEYSTORE_PASSWORD 读取或交互式输入
+//	env:<变量名>     环境变量中的十六进制私钥
+//	file:<路径>      文件中的十六进制私钥，文件不能对属组或其他用户开放任何权限
+func Load(spec string) (*Key, error) {
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok || arg == "" {
+		return nil, fmt.Errorf("无效的密钥来源 %q，应为 keystore:<路径>、env:<变量名> 或 file:<路径>", spec)
+	}
+	var (
+		privateKey *ecdsa.PrivateKey
+		err        error
+	)
+	switch kind {
+	case "keystore":
+		privateKey, err = loadKeystore(arg)
+	case "env":
+		privateKey, err = loadEnv(arg)
+	case "file":
+		privateKey, err = loadFile(arg)
+	default:
+		return nil, fmt.Errorf("不支持的密钥来源类型 %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		PrivateKey: privateKey,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		Source:     spec,
+	}, nil
+}
+
+// SignTx 使用给定的签名规则对交易签名
+func (k *Key) SignTx(tx *types.Transaction, signer types.Signer) (*types.Transaction, error) {
+	return types.SignTx(tx, signer, k.PrivateKey)
+}
+
+// SignSetCode 对 EIP-7702 授权签名，授权该私钥的账户把代码委托给 auth.Address
+func (k *Key) SignSetCode(auth types.SetCodeAuthorization) (types.SetCodeAuthorization, error) {
+	return types.SignSetCode(k.PrivateKey, auth)
+}
+
+// TransactOpts 创建用于合约绑定代码的交易授权对象
+func (k *Key) TransactOpts(chainID *big.Int) (*bind.TransactOpts, error) {
+	return bind.NewKeyedTransactorWithChainID(k.PrivateKey, chainID)
+}
+
+// loadKeystore 解密 keystore JSON 文件
+func loadKeystore(path string) (*ecdsa.PrivateKey, error) {
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 keystore 文件失败: %w", err)
+	}
+	passphrase, ok := os.LookupEnv(PassphraseEnv)
+	if !ok {
+		passphrase, err = prompt.Stdin.PromptPassword(fmt.Sprintf("请输入 %s 的密码: ", path))
+		if err != nil {
+			return nil, fmt.Errorf("读取 keystore 密码失败: %w", err)
+		}
+	}
+	key, err := keystore.DecryptKey(keyjson, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("解密 keystore 失败: %w", err)
+	}
+	return key.PrivateKey, nil
+}
+
+// loadEnv 从环境变量读取十六进制私钥
+func loadEnv(name string) (*ecdsa.PrivateKey, error) {
+	hexkey, ok := os.LookupEnv(name)
+	if !ok || hexkey == "" {
+		return nil, fmt.Errorf("环境变量 %s 未设置", name)
+	}
+	privateKey, err := parseHex(hexkey)
+	if err != nil {
+		return nil, fmt.Errorf("环境变量 %s 中的私钥无效: %w", name, err)
+	}
+	return privateKey, nil
+}
+
+// loadFile 从权限受限的文件读取十六进制私钥
+func loadFile(path string) (*ecdsa.PrivateKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+	// Windows 不使用 Unix 权限位，无法以同样方式检查
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("私钥文件 %s 的权限 %04o 过于宽松，请执行 chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+	privateKey, err := parseHex(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("私钥文件 %s 中的私钥无效: %w", path, err)
+	}
+	return privateKey, nil
+}
+
+// parseHex 解析可带 0x 前缀和首尾空白的十六进制私钥
+func parseHex(s string) (*ecdsa.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	return crypto.HexToECDSA(s)
+}
@@ -0,0 +1,205 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxRetries 是 Submit 遇到 nonce 错误后重新同步并重试的最大次数
+const MaxRetries = 3
+
+// Backend 是同步 nonce 所需的节点接口，测试中可以用固定的 pending nonce 代替节点
+type Backend interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// Manager 在本地为每个账户分配 nonce，可供多个 goroutine 并发使用。
+// 首次使用某个账户时从节点读取 pending nonce，之后在本地递增；发送失败的 nonce 通过 Release 归还后
+// 优先分配，避免留下空洞；节点报告 nonce 过低或交易已存在时通过 Resync 与节点重新同步
+type Manager struct {
+	backend Backend
+
+	mu       sync.Mutex
+	accounts map[common.Address]*account
+}
+
+// account 是单个账户的 nonce 状态，由自身的锁保护，不同账户之间互不阻塞
+type account struct {
+	mu       sync.Mutex
+	synced   bool
+	next     uint64              // 下一个未分配过的 nonce
+	inflight map[uint64]struct{} // 已分配但尚未确认发送结果的 nonce
+	released []uint64            // 已归还、需要优先重新分配的 nonce，升序
+}
+
+// NewManager 创建 nonce 管理器
+func NewManager(backend Backend) *Manager {
+	return &Manager{backend: backend, accounts: make(map[common.Address]*account)}
+}
+
+func (m *Manager) account(addr common.Address) *account {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc, ok := m.accounts[addr]
+	if !ok {
+		acc = &account{inflight: make(map[uint64]struct{})}
+		m.accounts[addr] = acc
+	}
+	return acc
+}
+
+// Next 为账户分配一个 nonce，优先分配已归还的 nonce。分配的 nonce 必须随后调用 Sent 或 Release
+func (m *Manager) Next(ctx context.Context, addr common.Address) (uint64, error) {
+	acc := m.account(addr)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	if !acc.synced {
+		pending, err := m.backend.PendingNonceAt(ctx, addr)
+		if err != nil {
+			return 0, fmt.Errorf("获取 nonce 失败: %w", err)
+		}
+		acc.next = pending
+		acc.synced = true
+	}
+	var n uint64
+	if len(acc.released) > 0 {
+		n, acc.released = acc.released[0], acc.released[1:]
+	} else {
+		n = acc.next
+		acc.next++
+	}
+	acc.inflight[n] = struct{}{}
+	return n, nil
+}
+
+// Sent 标记 nonce 对应的交易已被节点接受
+func (m *Manager) Sent(addr common.Address, n uint64) {
+	acc := m.account(addr)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	delete(acc.inflight, n)
+}
+
+// Release 归还未能发送的 nonce，之后优先分配给下一笔交易以免留下空洞。
+// 归还的 nonce 只会被之后的 Next 重新分配，若此后不再发送交易而更高的 nonce 已经发出，
+// 这些交易会一直卡在交易池中；调用方结束前应通过 Gaps 检查并提示
+func (m *Manager) Release(addr common.Address, n uint64) {
+	acc := m.account(addr)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	delete(acc.inflight, n)
+	acc.addReleased(n)
+}
+
+// Gaps 返回已归还但尚未重新分配、且低于已分配的最高 nonce 的 nonce，升序。
+// 这些 nonce 是空洞，之后不再用它们发送交易时，更高 nonce 的交易将无法上链
+func (m *Manager) Gaps(addr common.Address) []uint64 {
+	acc := m.account(addr)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	// 紧邻 next 的连续归还 nonce 之上没有发出的交易，不算空洞
+	top, i := acc.next, len(acc.released)
+	for i > 0 && acc.released[i-1]+1 == top {
+		top--
+		i--
+	}
+	return append([]uint64(nil), acc.released[:i]...)
+}
+
+// Resync 从节点重新读取 pending nonce。节点的 pending nonce 超过本地时跳到节点的值；
+// 低于本地时说明从 pending 起的交易已丢失，其中没有正在发送中的 nonce 都作为空洞优先重新分配
+func (m *Manager) Resync(ctx context.Context, addr common.Address) error {
+	acc := m.account(addr)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	pending, err := m.backend.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("同步 nonce 失败: %w", err)
+	}
+	acc.synced = true
+
+	// 低于 pending nonce 的都已被使用，不能再分配
+	kept := acc.released[:0]
+	for _, n := range acc.released {
+		if n >= pending {
+			kept = append(kept, n)
+		}
+	}
+	acc.released = kept
+
+	if pending >= acc.next {
+		acc.next = pending
+		return nil
+	}
+	for n := pending; n < acc.next; n++ {
+		if _, busy := acc.inflight[n]; !busy {
+			acc.addReleased(n)
+		}
+	}
+	return nil
+}
+
+// Submit 分配 nonce 并调用 send 发送交易。send 返回 nonce 过低的错误时重新同步并以新的 nonce 重试，
+// 最多 MaxRetries 次；返回交易已存在的错误时视为已发送；其他错误归还 nonce 后原样返回
+func (m *Manager) Submit(ctx context.Context, addr common.Address, send func(nonce uint64) error) (uint64, error) {
+	for attempt := 0; ; attempt++ {
+		n, err := m.Next(ctx, addr)
+		if err != nil {
+			return 0, err
+		}
+		err = send(n)
+		switch {
+		case err == nil:
+			m.Sent(addr, n)
+			return n, nil
+		case IsAlreadyKnown(err):
+			// 同一笔交易已在交易池中，等同于发送成功，但本地状态可能落后于节点
+			m.Sent(addr, n)
+			if err := m.Resync(ctx, addr); err != nil {
+				return n, err
+			}
+			return n, nil
+		case IsNonceTooLow(err) && attempt < MaxRetries:
+			// 该 nonce 已被其他交易使用，不归还
+			m.Sent(addr, n)
+			if err := m.Resync(ctx, addr); err != nil {
+				return 0, err
+			}
+		default:
+			if IsNonceTooLow(err) {
+				m.Sent(addr, n)
+			} else {
+				m.Release(addr, n)
+			}
+			return 0, err
+		}
+	}
+}
+
+func (acc *account) addReleased(n uint64) {
+	i := sort.Search(len(acc.released), func(i int) bool { return acc.released[i] >= n })
+	if i < len(acc.released) && acc.released[i] == n {
+		return
+	}
+	acc.released = append(acc.released, 0)
+	copy(acc.released[i+1:], acc.released[i:])
+	acc.released[i] = n
+}
+
+// IsNonceTooLow 判断节点是否因 nonce 已被使用而拒绝交易。错误经过 RPC 传递后只保留消息文本，因此按文本匹配
+func IsNonceTooLow(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "nonce too low")
+}
+
+// IsAlreadyKnown 判断节点是否因交易池中已有同一笔交易而拒绝交易
+func IsAlreadyKnown(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "already known")
+}
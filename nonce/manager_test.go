@@ -0,0 +1,216 @@
+package nonce
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeBackend 返回可修改的 pending nonce
+type fakeBackend struct {
+	mu      sync.Mutex
+	pending uint64
+	calls   int
+}
+
+func (b *fakeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	return b.pending, nil
+}
+
+func (b *fakeBackend) set(pending uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = pending
+}
+
+var testAddr = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+func next(t *testing.T, m *Manager, count int) []uint64 {
+	t.Helper()
+	var got []uint64
+	for i := 0; i < count; i++ {
+		n, err := m.Next(context.Background(), testAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, n)
+	}
+	return got
+}
+
+func TestNextSyncsOnce(t *testing.T) {
+	backend := &fakeBackend{pending: 5}
+	m := NewManager(backend)
+	if got, want := next(t, m, 3), []uint64{5, 6, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+	if backend.calls != 1 {
+		t.Fatalf("PendingNonceAt called %d times, want 1", backend.calls)
+	}
+}
+
+func TestReleaseReusedFirst(t *testing.T) {
+	m := NewManager(&fakeBackend{pending: 0})
+	next(t, m, 4)
+	m.Release(testAddr, 2)
+	m.Release(testAddr, 1)
+	if got, want := next(t, m, 3), []uint64{1, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestResyncFillsGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		inflight []uint64 // 重新同步时仍在发送中的 nonce
+		pending  uint64
+		want     []uint64
+	}{
+		{"all lost", nil, 5, []uint64{5, 6, 7, 8}},
+		{"middle in flight", []uint64{6}, 5, []uint64{5, 7, 8, 9}},
+		{"partially mined", nil, 6, []uint64{6, 7, 8, 9}},
+		{"node ahead", nil, 10, []uint64{10, 11, 12, 13}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{pending: 5}
+			m := NewManager(backend)
+			for _, n := range next(t, m, 3) {
+				if !contains(tt.inflight, n) {
+					m.Sent(testAddr, n)
+				}
+			}
+			backend.set(tt.pending)
+			if err := m.Resync(context.Background(), testAddr); err != nil {
+				t.Fatal(err)
+			}
+			if got := next(t, m, 4); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResyncDropsUsedReleased(t *testing.T) {
+	backend := &fakeBackend{pending: 0}
+	m := NewManager(backend)
+	next(t, m, 3)
+	m.Release(testAddr, 0)
+	m.Sent(testAddr, 1)
+	m.Sent(testAddr, 2)
+	backend.set(3)
+	if err := m.Resync(context.Background(), testAddr); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := next(t, m, 1), []uint64{3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestSubmitRetriesNonceTooLow(t *testing.T) {
+	backend := &fakeBackend{pending: 0}
+	m := NewManager(backend)
+	var tried []uint64
+	n, err := m.Submit(context.Background(), testAddr, func(n uint64) error {
+		tried = append(tried, n)
+		if n < 2 {
+			backend.set(2)
+			return errors.New("nonce too low: next nonce 2, tx nonce 0")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || !reflect.DeepEqual(tried, []uint64{0, 2}) {
+		t.Fatalf("Submit = %d after %v, want 2 after [0 2]", n, tried)
+	}
+}
+
+func TestConcurrentNextRelease(t *testing.T) {
+	const workers, rounds = 8, 50
+	m := NewManager(&fakeBackend{pending: 100})
+	var (
+		mu   sync.Mutex
+		sent = make(map[uint64]bool)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				n, err := m.Next(context.Background(), testAddr)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// 每隔一次归还 nonce，模拟发送失败
+				if (w+i)%2 == 0 {
+					m.Release(testAddr, n)
+					continue
+				}
+				mu.Lock()
+				if sent[n] {
+					t.Errorf("nonce %d allocated twice", n)
+				}
+				sent[n] = true
+				mu.Unlock()
+				m.Sent(testAddr, n)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// 剩余归还的 nonce 按升序重新分配，与发送成功的 nonce 合起来从 100 起连续，不留空洞
+	var top uint64 = 100
+	for n := range sent {
+		if n >= top {
+			top = n + 1
+		}
+	}
+	for n := next(t, m, 1)[0]; n < top; n = next(t, m, 1)[0] {
+		if sent[n] {
+			t.Fatalf("nonce %d allocated twice", n)
+		}
+		sent[n] = true
+	}
+	for n := uint64(100); n < top; n++ {
+		if !sent[n] {
+			t.Fatalf("nonce %d never allocated", n)
+		}
+	}
+}
+
+func TestManagerGaps(t *testing.T) {
+	m := NewManager(&fakeBackend{pending: 100})
+	next(t, m, 5) // 100..104
+	m.Release(testAddr, 101)
+	m.Release(testAddr, 103)
+	m.Release(testAddr, 104)
+	if got := m.Gaps(testAddr); !reflect.DeepEqual(got, []uint64{101}) {
+		t.Fatalf("gaps = %v, want [101]", got)
+	}
+	if n := next(t, m, 1)[0]; n != 101 {
+		t.Fatalf("next = %d, want 101", n)
+	}
+	if got := m.Gaps(testAddr); len(got) != 0 {
+		t.Fatalf("gaps = %v, want none", got)
+	}
+}
+
+func contains(list []uint64, n uint64) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
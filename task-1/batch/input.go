@@ -0,0 +1,184 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"practical-task/units"
+)
+
+// Transfer 是批量转账文件中的一笔转账
+type Transfer struct {
+	Index  int            // 在文件中的序号，从 0 开始
+	Line   int            // 所在行号（JSON 文件为数组下标 + 1）
+	To     common.Address // 接收方地址
+	Amount *big.Int       // 转账金额，单位 wei
+}
+
+// ValidationError 汇总转账文件中的全部错误，便于一次修正
+type ValidationError struct {
+	Errs []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("转账文件中有 %d 处错误:\n  %s", len(e.Errs), strings.Join(e.Errs, "\n  "))
+}
+
+// jsonTransfer 是 JSON 文件中的一条记录
+type jsonTransfer struct {
+	To     string     `json:"to"`
+	Amount jsonAmount `json:"amount"`
+	Unit   string     `json:"unit"`
+}
+
+// jsonAmount 是 JSON 记录中的金额，可以是数字（如 0.5）或字符串（如 "0.5"、"0.5 ether"），
+// 保留原始文本，与 CSV 中的金额按相同规则解析，数字不经过浮点数
+type jsonAmount string
+
+func (a *jsonAmount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = jsonAmount(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("金额应为数字或字符串，实际为 %s", data)
+	}
+	*a = jsonAmount(n)
+	return nil
+}
+
+// LoadFile 读取并校验批量转账文件，按扩展名区分格式：
+//
+//	.csv   每行 "地址,金额[,单位]"，可有 address 表头，# 开头的行为注释
+//	.json  [{"to": "地址", "amount": "金额", "unit": "单位"}, ...]
+//
+// 金额可以带单位后缀（如 "0.5 ether"、"20 gwei"），未指定单位时使用 defaultUnit。
+// 地址必须是有效的 EIP-55 校验和格式，金额必须为正且不能超出单位精度。
+// 所有记录都会被校验，存在错误时返回 *ValidationError
+func LoadFile(path, defaultUnit string) ([]Transfer, error) {
+	if _, err := units.Decimals(defaultUnit); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开转账文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var rows []row
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		r := csv.NewReader(f)
+		r.Comment = '#'
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = true
+		for first := true; ; first = false {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+			}
+			if first && isHeader(record[0]) {
+				continue
+			}
+			line, _ := r.FieldPos(0)
+			rows = append(rows, row{line: line, fields: record})
+		}
+	case ".json":
+		var records []jsonTransfer
+		if err := json.NewDecoder(f).Decode(&records); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+		}
+		for i, rec := range records {
+			rows = append(rows, row{line: i + 1, fields: []string{rec.To, string(rec.Amount), rec.Unit}})
+		}
+	default:
+		return nil, fmt.Errorf("不支持的转账文件格式 %q，请使用 .csv 或 .json", filepath.Ext(path))
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("转账文件中没有转账记录")
+	}
+
+	var (
+		transfers []Transfer
+		invalid   ValidationError
+	)
+	for i, r := range rows {
+		to, amount, err := r.parse(defaultUnit)
+		if err != nil {
+			invalid.Errs = append(invalid.Errs, fmt.Sprintf("第 %d 行: %v", r.line, err))
+			continue
+		}
+		transfers = append(transfers, Transfer{Index: i, Line: r.line, To: to, Amount: amount})
+	}
+	if len(invalid.Errs) > 0 {
+		return nil, &invalid
+	}
+	return transfers, nil
+}
+
+// row 是转账文件中的一条原始记录：地址、金额和可选的单位
+type row struct {
+	line   int
+	fields []string
+}
+
+func isHeader(field string) bool {
+	switch strings.ToLower(strings.TrimSpace(field)) {
+	case "address", "to", "recipient":
+		return true
+	}
+	return false
+}
+
+func (r row) parse(defaultUnit string) (common.Address, *big.Int, error) {
+	if len(r.fields) < 2 || len(r.fields) > 3 {
+		return common.Address{}, nil, fmt.Errorf("应为 2 或 3 列，实际为 %d 列", len(r.fields))
+	}
+	to, err := parseAddress(r.fields[0])
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	amount := strings.TrimSpace(r.fields[1])
+	var wei *big.Int
+	if len(r.fields) == 3 && strings.TrimSpace(r.fields[2]) != "" {
+		wei, err = units.ParseUnit(amount, r.fields[2])
+	} else {
+		wei, err = units.ParseAmount(amount, defaultUnit)
+	}
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if wei.Sign() <= 0 {
+		return common.Address{}, nil, fmt.Errorf("金额 %q 必须大于 0", amount)
+	}
+	return to, wei, nil
+}
+
+// parseAddress 解析地址并校验 EIP-55 校验和
+func parseAddress(s string) (common.Address, error) {
+	s = strings.TrimSpace(s)
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("无效的地址 %q", s)
+	}
+	addr := common.HexToAddress(s)
+	if addr.Hex() != s {
+		return common.Address{}, fmt.Errorf("地址 %q 的校验和无效，应为 %s", s, addr.Hex())
+	}
+	if addr == (common.Address{}) {
+		return common.Address{}, errors.New("不能向零地址转账")
+	}
+	return addr, nil
+}
@@ -0,0 +1,65 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	alice = "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"
+	bob   = "0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileAmounts(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		want                []string // 各笔转账的金额，单位 wei
+	}{
+		{"json unit suffix", "t.json", `[{"to":"` + alice + `","amount":"0.5 ether"}]`, []string{"500000000000000000"}},
+		{"json bare number", "t.json", `[{"to":"` + alice + `","amount":0.25}]`, []string{"250000000000000000"}},
+		{"json numeric string", "t.json", `[{"to":"` + alice + `","amount":"2"}]`, []string{"2000000000000000000"}},
+		{"json unit precision overflow", "t.json", `[{"to":"` + alice + `","amount":20,"unit":"gwei"},{"to":"` + bob + `","amount":"1.5","unit":"wei"}]`, nil},
+		{"json amount and unit", "t.json", `[{"to":"` + alice + `","amount":"20","unit":"gwei"},{"to":"` + bob + `","amount":1000,"unit":"wei"}]`, []string{"20000000000", "1000"}},
+		{"csv unit suffix", "t.csv", "address,amount\n" + alice + ",0.5 ether\n" + bob + ",20,gwei\n", []string{"500000000000000000", "20000000000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers, err := LoadFile(writeFile(t, tt.file, tt.content), "ether")
+			if tt.want == nil {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("LoadFile error = %v, want *ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(transfers) != len(tt.want) {
+				t.Fatalf("got %d transfers, want %d", len(transfers), len(tt.want))
+			}
+			for i, tr := range transfers {
+				if tr.Amount.String() != tt.want[i] {
+					t.Errorf("transfer %d amount = %s, want %s", i, tr.Amount, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileRejectsInvalidJSONAmount(t *testing.T) {
+	if _, err := LoadFile(writeFile(t, "t.json", `[{"to":"`+alice+`","amount":true}]`), "ether"); err == nil {
+		t.Fatal("LoadFile accepted a boolean amount")
+	}
+}
@@ -0,0 +1,173 @@
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Status 是一笔转账的处理状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 尚未发送
+	StatusSigned    Status = "signed"    // 已签名，发送结果未知，恢复时需要查询节点
+	StatusSent      Status = "sent"      // 节点已接受，等待上链
+	StatusConfirmed Status = "confirmed" // 已上链且执行成功
+	StatusFailed    Status = "failed"    // 已上链但执行失败
+	StatusReplaced  Status = "replaced"  // nonce 已被另一笔交易（如加速或取消）使用，需要人工核对，恢复时不再发送
+	StatusError     Status = "error"     // 发送失败或交易被丢弃，可以重试
+)
+
+// Result 是一笔转账的处理结果
+type Result struct {
+	Index   int            `json:"index"`
+	Line    int            `json:"line"`
+	To      common.Address `json:"to"`
+	Amount  *big.Int       `json:"amount"` // 单位 wei
+	Status  Status         `json:"status"`
+	Nonce   *uint64        `json:"nonce,omitempty"`
+	Hash    *common.Hash   `json:"hash,omitempty"`
+	Block   uint64         `json:"block,omitempty"`
+	GasUsed uint64         `json:"gasUsed,omitempty"`
+	Fee     *big.Int       `json:"fee,omitempty"` // 实际支付的费用，单位 wei
+	Error   string         `json:"error,omitempty"`
+}
+
+// Progress 是可恢复的批量转账进度，每次更新后都写入进度文件，中断后以相同的转账文件重新运行即可继续
+type Progress struct {
+	path string
+
+	Input   string    `json:"input"` // 转账列表的摘要，防止用不同的文件恢复
+	Results []*Result `json:"results"`
+}
+
+// OpenProgress 打开进度文件，不存在时按转账列表创建新的进度。
+// 进度文件对应的转账列表与当前列表不一致时返回错误
+func OpenProgress(path string, transfers []Transfer) (*Progress, error) {
+	digest := fingerprint(transfers)
+	p := &Progress{path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		p.Input = digest
+		for _, t := range transfers {
+			p.Results = append(p.Results, &Result{
+				Index:  t.Index,
+				Line:   t.Line,
+				To:     t.To,
+				Amount: t.Amount,
+				Status: StatusPending,
+			})
+		}
+		return p, p.Save()
+	case err != nil:
+		return nil, fmt.Errorf("读取进度文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("解析进度文件 %s 失败: %w", path, err)
+	}
+	if p.Input != digest || len(p.Results) != len(transfers) {
+		return nil, fmt.Errorf("进度文件 %s 与当前转账文件不一致，请确认文件或删除进度文件后重新开始", path)
+	}
+	return p, nil
+}
+
+// Save 将进度写入进度文件，先写临时文件再重命名，避免中断时留下不完整的文件
+func (p *Progress) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), ".progress-*")
+	if err != nil {
+		return fmt.Errorf("写入进度文件失败: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入进度文件失败: %w", err)
+	}
+	return nil
+}
+
+// Counts 统计各状态的转账数量
+func (p *Progress) Counts() map[Status]int {
+	counts := make(map[Status]int)
+	for _, r := range p.Results {
+		counts[r.Status]++
+	}
+	return counts
+}
+
+// fingerprint 计算转账列表的摘要
+func fingerprint(transfers []Transfer) string {
+	h := sha256.New()
+	for _, t := range transfers {
+		fmt.Fprintf(h, "%d,%s,%s\n", t.Index, t.To.Hex(), t.Amount)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReportFormat 根据报告文件的扩展名返回报告格式（csv 或 json），不支持的扩展名返回错误
+func ReportFormat(path string) (string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != "csv" && format != "json" {
+		return "", fmt.Errorf("不支持的报告格式 %q，可用格式: csv、json", filepath.Ext(path))
+	}
+	return format, nil
+}
+
+// WriteReport 输出批量转账结果报告，format 为 csv 或 json
+func WriteReport(w io.Writer, format string, results []*Result) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"index", "line", "to", "amount_wei", "status", "nonce", "hash", "block", "gas_used", "fee_wei", "error"})
+		for _, r := range results {
+			nonce, hash, block, gasUsed, fee := "", "", "", "", ""
+			if r.Nonce != nil {
+				nonce = strconv.FormatUint(*r.Nonce, 10)
+			}
+			if r.Hash != nil {
+				hash = r.Hash.Hex()
+			}
+			if r.Block > 0 {
+				block = strconv.FormatUint(r.Block, 10)
+				gasUsed = strconv.FormatUint(r.GasUsed, 10)
+			}
+			if r.Fee != nil {
+				fee = r.Fee.String()
+			}
+			cw.Write([]string{
+				strconv.Itoa(r.Index), strconv.Itoa(r.Line), r.To.Hex(), r.Amount.String(),
+				string(r.Status), nonce, hash, block, gasUsed, fee, r.Error,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("不支持的报告格式 %q，可用格式: csv、json", format)
+}
@@ -0,0 +1,276 @@
+package blockquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 缓存目录下的子目录
+const (
+	cacheHeaders    = "headers"    // <区块哈希>.rlp
+	cacheBlocks     = "blocks"     // <区块哈希>.rlp
+	cacheReceipts   = "receipts"   // <区块哈希>.json，整个区块的回执
+	cacheTxReceipts = "txreceipts" // <交易哈希>.json，单笔交易的回执
+	cacheNumbers    = "numbers"    // <区块号>，内容为规范链上该高度的区块哈希
+)
+
+// Cache 是已最终确定（finalized）区块的本地磁盘缓存。它包装一个 Backend 并同样实现 Backend：
+// 最终确定的区块头、区块体和回执按区块号与哈希持久化到磁盘，之后的查询不再请求节点；
+// 尚未最终确定的区块可能被重组替换，始终直接请求节点且不写入缓存
+type Cache struct {
+	Backend
+
+	dir       string
+	finalized uint64 // 打开缓存时节点报告的最终确定区块号
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// OpenCache 在 dir/<链ID> 下打开缓存，按链 ID 分目录以免不同网络的数据混用。
+// 打开时会查询节点当前的最终确定区块，并清除高于该区块的区块号索引
+func OpenCache(ctx context.Context, backend Backend, dir string, chainID *big.Int) (*Cache, error) {
+	c := &Cache{Backend: backend, dir: filepath.Join(dir, chainID.String())}
+	for _, sub := range []string{cacheHeaders, cacheBlocks, cacheReceipts, cacheTxReceipts, cacheNumbers} {
+		if err := os.MkdirAll(filepath.Join(c.dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+		}
+	}
+	header, err := backend.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		return nil, fmt.Errorf("获取最终确定区块失败: %w", err)
+	}
+	c.finalized = header.Number.Uint64()
+	if err := c.prune(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Finalized 返回缓存所依据的最终确定区块号
+func (c *Cache) Finalized() uint64 {
+	return c.finalized
+}
+
+// Stats 返回缓存命中与未命中次数
+func (c *Cache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// HeaderByNumber 优先从缓存读取最终确定的区块头
+func (c *Cache) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if hash, ok := c.lookupNumber(number); ok {
+		var header types.Header
+		if c.readRLP(cacheHeaders, hash.Hex(), &header) {
+			return &header, nil
+		}
+	}
+	c.misses.Add(1)
+	header, err := c.Backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if c.final(header.Number) && number != nil && number.Sign() >= 0 {
+		c.writeRLP(cacheHeaders, header.Hash().Hex(), header)
+		c.writeNumber(header.Number.Uint64(), header.Hash())
+	}
+	return header, nil
+}
+
+// BlockByNumber 优先从缓存读取最终确定的完整区块
+func (c *Cache) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if hash, ok := c.lookupNumber(number); ok {
+		if block, ok := c.readBlock(hash); ok {
+			return block, nil
+		}
+	}
+	c.misses.Add(1)
+	block, err := c.Backend.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if c.final(block.Number()) && number != nil && number.Sign() >= 0 {
+		c.writeBlock(block)
+		c.writeNumber(block.NumberU64(), block.Hash())
+	}
+	return block, nil
+}
+
+// BlockByHash 优先从缓存读取最终确定的完整区块。按哈希查询到的区块不一定在规范链上，
+// 因此只按哈希缓存，不写入区块号索引
+func (c *Cache) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if block, ok := c.readBlock(hash); ok {
+		return block, nil
+	}
+	c.misses.Add(1)
+	block, err := c.Backend.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if c.final(block.Number()) {
+		c.writeBlock(block)
+	}
+	return block, nil
+}
+
+// BlockReceipts 优先从缓存读取最终确定区块的全部回执
+func (c *Cache) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	hash, ok := blockNrOrHash.Hash()
+	if !ok {
+		if number, isNumber := blockNrOrHash.Number(); isNumber && number >= 0 {
+			hash, ok = c.lookupNumber(big.NewInt(number.Int64()))
+		}
+	}
+	if ok {
+		var receipts []*types.Receipt
+		if c.readJSON(cacheReceipts, hash.Hex(), &receipts) {
+			return receipts, nil
+		}
+	}
+	c.misses.Add(1)
+	receipts, err := c.Backend.BlockReceipts(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) > 0 && c.final(receipts[0].BlockNumber) {
+		c.writeJSON(cacheReceipts, receipts[0].BlockHash.Hex(), receipts)
+	}
+	return receipts, nil
+}
+
+// TransactionReceipt 优先从缓存读取最终确定区块中交易的回执
+func (c *Cache) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt types.Receipt
+	if c.readJSON(cacheTxReceipts, txHash.Hex(), &receipt) {
+		return &receipt, nil
+	}
+	c.misses.Add(1)
+	r, err := c.Backend.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if c.final(r.BlockNumber) {
+		c.writeJSON(cacheTxReceipts, txHash.Hex(), r)
+	}
+	return r, nil
+}
+
+// final 判断区块号是否不高于最终确定区块
+func (c *Cache) final(number *big.Int) bool {
+	return number != nil && number.IsUint64() && number.Uint64() <= c.finalized
+}
+
+// lookupNumber 通过区块号索引查找最终确定区块的哈希，标签和未最终确定的区块号总是未命中
+func (c *Cache) lookupNumber(number *big.Int) (common.Hash, bool) {
+	if number == nil || number.Sign() < 0 || !c.final(number) {
+		return common.Hash{}, false
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, cacheNumbers, number.String()))
+	if err != nil {
+		return common.Hash{}, false
+	}
+	return common.HexToHash(strings.TrimSpace(string(data))), true
+}
+
+// prune 清除高于最终确定区块的区块号索引。正常情况下不会写入这类索引，
+// 但缓存目录可能来自节点状态不同的另一台机器
+func (c *Cache) prune() error {
+	dir := filepath.Join(c.dir, cacheNumbers)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取缓存索引失败: %w", err)
+	}
+	for _, entry := range entries {
+		number, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil || number > c.finalized {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("清除缓存索引失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cache) readBlock(hash common.Hash) (*types.Block, bool) {
+	var block types.Block
+	if !c.readRLP(cacheBlocks, hash.Hex(), &block) {
+		return nil, false
+	}
+	return &block, true
+}
+
+func (c *Cache) writeBlock(block *types.Block) {
+	c.writeRLP(cacheBlocks, block.Hash().Hex(), block)
+	c.writeRLP(cacheHeaders, block.Hash().Hex(), block.Header())
+}
+
+func (c *Cache) writeNumber(number uint64, hash common.Hash) {
+	c.write(filepath.Join(cacheNumbers, strconv.FormatUint(number, 10)), []byte(hash.Hex()))
+}
+
+func (c *Cache) readRLP(kind, key string, v interface{}) bool {
+	data, err := os.ReadFile(filepath.Join(c.dir, kind, key+".rlp"))
+	if err != nil || rlp.DecodeBytes(data, v) != nil {
+		return false
+	}
+	c.hits.Add(1)
+	return true
+}
+
+func (c *Cache) writeRLP(kind, key string, v interface{}) {
+	data, err := rlp.EncodeToBytes(v)
+	if err == nil {
+		c.write(filepath.Join(kind, key+".rlp"), data)
+	}
+}
+
+func (c *Cache) readJSON(kind, key string, v interface{}) bool {
+	data, err := os.ReadFile(filepath.Join(c.dir, kind, key+".json"))
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return false
+	}
+	c.hits.Add(1)
+	return true
+}
+
+func (c *Cache) writeJSON(kind, key string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		c.write(filepath.Join(kind, key+".json"), data)
+	}
+}
+
+// write 先写临时文件再重命名，避免并发读取到写了一半的文件。
+// 缓存写入失败只会导致下次未命中，因此忽略错误
+func (c *Cache) write(name string, data []byte) {
+	path := filepath.Join(c.dir, name)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,205 @@
+package blockquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FollowBackend 是跟踪新区块所需的节点接口，测试中可以用内存中的区块头代替节点
+type FollowBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// Reorg 描述一次链重组
+type Reorg struct {
+	Ancestor *types.Header   // 新旧两条链的共同祖先，重组深度超出跟踪范围时为 nil
+	Replaced []*types.Header // 被替换的旧区块，按区块号升序
+	Added    []*types.Header // 新加入的区块，按区块号升序
+}
+
+// Depth 返回重组深度，即被替换的区块数
+func (r *Reorg) Depth() int {
+	return len(r.Replaced)
+}
+
+// Follower 跟踪链上的新区块，并通过比对父哈希检测链重组
+type Follower struct {
+	backend FollowBackend
+
+	PollInterval time.Duration // 轮询间隔，订阅不可用时使用
+	History      int           // 保留的最近区块数，决定可检测的最大重组深度
+
+	OnBlock  func(header *types.Header) error // 每个新的规范区块调用一次，按区块号升序
+	OnReorg  func(reorg *Reorg) error         // 检测到重组时在 OnBlock 之前调用
+	OnNotice func(msg string)                 // 订阅失败、回退到轮询等状态通知
+
+	chain []*types.Header // 本地跟踪的规范链，按区块号升序
+}
+
+// NewFollower 创建区块跟踪器
+func NewFollower(backend FollowBackend) *Follower {
+	return &Follower{
+		backend:      backend,
+		PollInterval: 12 * time.Second,
+		History:      128,
+	}
+}
+
+// Run 持续跟踪新区块直到 ctx 被取消。优先通过 SubscribeNewHead 订阅（需要 WebSocket
+// 或 IPC 连接），订阅不可用或中断时回退为按 PollInterval 轮询最新区块
+func (f *Follower) Run(ctx context.Context) error {
+	heads := make(chan *types.Header, 16)
+	sub, err := f.backend.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		f.notice(fmt.Sprintf("订阅新区块失败（%v），回退为每 %s 轮询一次", err, f.PollInterval))
+		return f.poll(ctx)
+	}
+	defer sub.Unsubscribe()
+	f.notice("已订阅新区块")
+
+	for {
+		select {
+		case head := <-heads:
+			if err := f.handle(ctx, head); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			f.notice(fmt.Sprintf("新区块订阅中断（%v），回退为每 %s 轮询一次", err, f.PollInterval))
+			return f.poll(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// poll 按固定间隔轮询最新区块头
+func (f *Follower) poll(ctx context.Context) error {
+	ticker := time.NewTicker(f.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		head, err := f.backend.HeaderByNumber(ctx, nil)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			f.notice(fmt.Sprintf("获取最新区块头失败: %v", err))
+		default:
+			if err := f.handle(ctx, head); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handle 处理一个新的链头：沿父哈希回溯直到与本地链相接，
+// 若本地链上有区块被替换则报告重组，然后依次报告新区块
+func (f *Follower) handle(ctx context.Context, head *types.Header) error {
+	if len(f.chain) == 0 {
+		f.chain = []*types.Header{head}
+		return f.emit(nil, []*types.Header{head})
+	}
+
+	oldest := f.chain[0].Number.Uint64()
+	if head.Number.Uint64() < oldest {
+		// 链头低于本地保留的最旧区块：落后的节点返回的旧链头，本地无从比对，不能当作重组
+		f.notice(fmt.Sprintf("忽略落后的链头 #%d（本地已跟踪到 #%d）", head.Number.Uint64(), f.chain[len(f.chain)-1].Number.Uint64()))
+		return nil
+	}
+
+	var (
+		added    []*types.Header
+		ancestor *types.Header
+		cur      = head
+	)
+	for {
+		number := cur.Number.Uint64()
+		if local := f.at(number); local != nil && local.Hash() == cur.Hash() {
+			ancestor = local
+			break
+		}
+		added = append([]*types.Header{cur}, added...)
+		if number == 0 || number <= oldest {
+			// 重组深度超出了本地保留的历史，无法确定共同祖先
+			break
+		}
+		parent, err := f.backend.HeaderByHash(ctx, cur.ParentHash)
+		if err != nil {
+			return fmt.Errorf("获取区块 %s 的父区块失败: %w", cur.Hash().Hex(), err)
+		}
+		cur = parent
+	}
+	if len(added) == 0 {
+		// 链头本身已在本地链上：负载均衡后的节点可能落后，返回较旧的链头，不是重组
+		return nil
+	}
+
+	// 被替换的旧区块：本地链上高于共同祖先的全部区块
+	var replaced []*types.Header
+	if ancestor == nil {
+		replaced, f.chain = f.chain, nil
+	} else {
+		// 复制一份，避免随后的 append 覆盖被替换的区块
+		keep := int(ancestor.Number.Uint64()-oldest) + 1
+		replaced = append(replaced, f.chain[keep:]...)
+		f.chain = f.chain[:keep]
+	}
+	f.chain = append(f.chain, added...)
+	if len(f.chain) > f.History && f.History > 0 {
+		f.chain = f.chain[len(f.chain)-f.History:]
+	}
+
+	if len(replaced) == 0 {
+		return f.emit(nil, added)
+	}
+	return f.emit(&Reorg{Ancestor: ancestor, Replaced: replaced, Added: added}, added)
+}
+
+// emit 依次调用重组与新区块回调
+func (f *Follower) emit(reorg *Reorg, added []*types.Header) error {
+	if reorg != nil && f.OnReorg != nil {
+		if err := f.OnReorg(reorg); err != nil {
+			return err
+		}
+	}
+	if f.OnBlock == nil {
+		return nil
+	}
+	for _, header := range added {
+		if err := f.OnBlock(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// at 返回本地链上指定区块号的区块头
+func (f *Follower) at(number uint64) *types.Header {
+	if len(f.chain) == 0 {
+		return nil
+	}
+	oldest := f.chain[0].Number.Uint64()
+	if number < oldest || number-oldest >= uint64(len(f.chain)) {
+		return nil
+	}
+	return f.chain[number-oldest]
+}
+
+func (f *Follower) notice(msg string) {
+	if f.OnNotice != nil {
+		f.OnNotice(msg)
+	}
+}
@@ -0,0 +1,149 @@
+package blockquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain 按哈希保存区块头，供 Follower 回溯父区块
+type fakeChain struct {
+	FollowBackend
+	headers map[common.Hash]*types.Header
+}
+
+func (c *fakeChain) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if h, ok := c.headers[hash]; ok {
+		return h, nil
+	}
+	return nil, fmt.Errorf("unknown header %s", hash.Hex())
+}
+
+// block 创建区块号为 number、父区块为 parent 的区块头，fork 用于区分同一高度的不同区块
+func (c *fakeChain) block(number int64, parent *types.Header, fork byte) *types.Header {
+	h := &types.Header{Number: big.NewInt(number), Extra: []byte{fork}}
+	if parent != nil {
+		h.ParentHash = parent.Hash()
+	}
+	c.headers[h.Hash()] = h
+	return h
+}
+
+// newTestFollower 创建记录回调事件的 Follower，事件形如 "block 2/0"（区块号/分叉）与 "reorg 1"（深度）
+func newTestFollower(chain *fakeChain, history int) (*Follower, *[]string) {
+	var events []string
+	f := NewFollower(chain)
+	f.History = history
+	f.OnBlock = func(h *types.Header) error {
+		events = append(events, fmt.Sprintf("block %d/%d", h.Number, h.Extra[0]))
+		return nil
+	}
+	f.OnReorg = func(r *Reorg) error {
+		events = append(events, fmt.Sprintf("reorg %d", r.Depth()))
+		return nil
+	}
+	return f, &events
+}
+
+func TestFollowerHandle(t *testing.T) {
+	chain := &fakeChain{headers: make(map[common.Hash]*types.Header)}
+	h0 := chain.block(0, nil, 0)
+	h1 := chain.block(1, h0, 0)
+	h2 := chain.block(2, h1, 0)
+	h3 := chain.block(3, h2, 0)
+	h4 := chain.block(4, h3, 0)
+	h2b := chain.block(2, h1, 1)
+	h3b := chain.block(3, h2b, 1)
+
+	tests := []struct {
+		name  string
+		heads []*types.Header
+		want  []string
+	}{
+		{"sequential", []*types.Header{h1, h2, h3}, []string{"block 1/0", "block 2/0", "block 3/0"}},
+		{"gap filled from parents", []*types.Header{h1, h4}, []string{"block 1/0", "block 2/0", "block 3/0", "block 4/0"}},
+		{"repeated head", []*types.Header{h1, h2, h2}, []string{"block 1/0", "block 2/0"}},
+		{"stale head", []*types.Header{h1, h2, h3, h1, h2}, []string{"block 1/0", "block 2/0", "block 3/0"}},
+		{"reorg", []*types.Header{h1, h2, h3, h3b}, []string{"block 1/0", "block 2/0", "block 3/0", "reorg 2", "block 2/1", "block 3/1"}},
+		{"shorter fork", []*types.Header{h1, h2, h3, h2b}, []string{"block 1/0", "block 2/0", "block 3/0", "reorg 2", "block 2/1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, events := newTestFollower(chain, 128)
+			for _, head := range tt.heads {
+				if err := f.handle(context.Background(), head); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(*events, tt.want) {
+				t.Fatalf("events = [%s], want [%s]", strings.Join(*events, ", "), strings.Join(tt.want, ", "))
+			}
+		})
+	}
+}
+
+func TestFollowerReorgBeyondHistory(t *testing.T) {
+	chain := &fakeChain{headers: make(map[common.Hash]*types.Header)}
+	h0 := chain.block(0, nil, 0)
+	h1 := chain.block(1, h0, 0)
+	h2 := chain.block(2, h1, 0)
+	h3 := chain.block(3, h2, 0)
+	h1b := chain.block(1, h0, 1)
+	h2b := chain.block(2, h1b, 1)
+	h3b := chain.block(3, h2b, 1)
+
+	f, _ := newTestFollower(chain, 2)
+	var reorg *Reorg
+	f.OnReorg = func(r *Reorg) error {
+		reorg = r
+		return nil
+	}
+	for _, head := range []*types.Header{h1, h2, h3, h3b} {
+		if err := f.handle(context.Background(), head); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reorg == nil {
+		t.Fatal("reorg not reported")
+	}
+	if reorg.Ancestor != nil {
+		t.Fatalf("ancestor = block %d, want nil beyond history", reorg.Ancestor.Number)
+	}
+	if got := len(reorg.Replaced); got != 2 {
+		t.Fatalf("replaced %d blocks, want 2", got)
+	}
+}
+
+func TestFollowerHeadBelowHistory(t *testing.T) {
+	chain := &fakeChain{headers: make(map[common.Hash]*types.Header)}
+	h0 := chain.block(0, nil, 0)
+	h1 := chain.block(1, h0, 0)
+	h2 := chain.block(2, h1, 0)
+	h3 := chain.block(3, h2, 0)
+	h1b := chain.block(1, h0, 1)
+
+	f, events := newTestFollower(chain, 2)
+	var notices int
+	f.OnNotice = func(string) { notices++ }
+	for _, head := range []*types.Header{h1, h2, h3, h1, h1b} {
+		if err := f.handle(context.Background(), head); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"block 1/0", "block 2/0", "block 3/0"}
+	if !reflect.DeepEqual(*events, want) {
+		t.Fatalf("events = [%s], want [%s]", strings.Join(*events, ", "), strings.Join(want, ", "))
+	}
+	if notices != 2 {
+		t.Fatalf("notices = %d, want 2", notices)
+	}
+	if got := f.chain[len(f.chain)-1]; got != h3 {
+		t.Fatalf("tip = block %d, want 3", got.Number)
+	}
+}
@@ -0,0 +1,86 @@
+package blockquery
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// HeaderDetail 是合并（The Merge）及 Dencun/Pectra 升级后区块头中有意义的字段
+type HeaderDetail struct {
+	StateRoot        common.Hash       `json:"stateRoot"`
+	TxRoot           common.Hash       `json:"transactionsRoot"`
+	ReceiptRoot      common.Hash       `json:"receiptsRoot"`
+	PrevRandao       common.Hash       `json:"prevRandao"` // 合并前为 mixHash
+	ExtraData        hexutil.Bytes     `json:"extraData"`
+	WithdrawalsHash  *common.Hash      `json:"withdrawalsRoot,omitempty"`  // 上海升级
+	Withdrawals      []*WithdrawalInfo `json:"withdrawals,omitempty"`      // 上海升级
+	BlobGasUsed      *uint64           `json:"blobGasUsed,omitempty"`      // Cancun 升级
+	ExcessBlobGas    *uint64           `json:"excessBlobGas,omitempty"`    // Cancun 升级
+	BlobBaseFee      *big.Int          `json:"blobBaseFee,omitempty"`      // 由 excessBlobGas 计算得出
+	ParentBeaconRoot *common.Hash      `json:"parentBeaconRoot,omitempty"` // Cancun 升级
+	RequestsHash     *common.Hash      `json:"requestsHash,omitempty"`     // Prague 升级
+}
+
+// WithdrawalInfo 是信标链提款信息，金额单位为 gwei
+type WithdrawalInfo struct {
+	Index     uint64         `json:"index"`
+	Validator uint64         `json:"validatorIndex"`
+	Address   common.Address `json:"address"`
+	Amount    uint64         `json:"amount"`
+}
+
+// ChainConfig 按链 ID 返回已知网络的链配置，未知网络返回 nil
+func ChainConfig(chainID *big.Int) *params.ChainConfig {
+	for _, config := range []*params.ChainConfig{
+		params.MainnetChainConfig,
+		params.SepoliaChainConfig,
+		params.HoleskyChainConfig,
+		params.HoodiChainConfig,
+	} {
+		if config.ChainID.Cmp(chainID) == 0 {
+			return config
+		}
+	}
+	return nil
+}
+
+// BlobBaseFee 计算区块的 blob 基础费用。区块早于 Cancun 升级、
+// 或链配置未知（无法确定 blob 费用参数）时返回 nil
+func BlobBaseFee(config *params.ChainConfig, header *types.Header) *big.Int {
+	if config == nil || header.ExcessBlobGas == nil || !config.IsCancun(header.Number, header.Time) {
+		return nil
+	}
+	return eip4844.CalcBlobFee(config, header)
+}
+
+// NewHeaderDetail 从完整区块中提取详细的区块头字段，config 用于计算 blob 基础费用，可为 nil
+func NewHeaderDetail(block *types.Block, config *params.ChainConfig) *HeaderDetail {
+	header := block.Header()
+	detail := &HeaderDetail{
+		StateRoot:        header.Root,
+		TxRoot:           header.TxHash,
+		ReceiptRoot:      header.ReceiptHash,
+		PrevRandao:       header.MixDigest,
+		ExtraData:        header.Extra,
+		WithdrawalsHash:  header.WithdrawalsHash,
+		BlobGasUsed:      header.BlobGasUsed,
+		ExcessBlobGas:    header.ExcessBlobGas,
+		BlobBaseFee:      BlobBaseFee(config, header),
+		ParentBeaconRoot: header.ParentBeaconRoot,
+		RequestsHash:     header.RequestsHash,
+	}
+	for _, w := range block.Withdrawals() {
+		detail.Withdrawals = append(detail.Withdrawals, &WithdrawalInfo{
+			Index:     w.Index,
+			Validator: w.Validator,
+			Address:   w.Address,
+			Amount:    w.Amount,
+		})
+	}
+	return detail
+}
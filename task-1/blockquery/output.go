@@ -0,0 +1,416 @@
+package blockquery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"practical-task/units"
+)
+
+// 支持的输出格式
+const (
+	FormatHuman  = "human"  // 人类可读的文本
+	FormatJSON   = "json"   // 单个 JSON 文档
+	FormatNDJSON = "ndjson" // 每行一个 JSON 对象
+	FormatCSV    = "csv"    // 带表头的 CSV
+)
+
+// BlockInfo 是输出用的区块信息，覆盖从区块头和区块体中读取的字段
+type BlockInfo struct {
+	Number     uint64         `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Time       uint64         `json:"timestamp"`
+	Difficulty *big.Int       `json:"difficulty"`
+	GasUsed    uint64         `json:"gasUsed"`
+	GasLimit   uint64         `json:"gasLimit"`
+	BaseFee    *big.Int       `json:"baseFee,omitempty"`
+	Miner      common.Address `json:"miner"`
+	TxCount    int            `json:"txCount"`
+
+	Transactions []*TxInfo     `json:"transactions,omitempty"` // 仅在请求交易列表时填充
+	FailedTxs    *int          `json:"failedTxs,omitempty"`    // 仅在请求回执时填充
+	Detail       *HeaderDetail `json:"detail,omitempty"`       // 仅在请求详细区块头时填充
+}
+
+// NewBlockInfo 从完整区块中提取输出信息
+func NewBlockInfo(block *types.Block) *BlockInfo {
+	header := block.Header()
+	return &BlockInfo{
+		Number:     header.Number.Uint64(),
+		Hash:       block.Hash(),
+		ParentHash: header.ParentHash,
+		Time:       header.Time,
+		Difficulty: header.Difficulty,
+		GasUsed:    header.GasUsed,
+		GasLimit:   header.GasLimit,
+		BaseFee:    header.BaseFee,
+		Miner:      header.Coinbase,
+		TxCount:    len(block.Transactions()),
+	}
+}
+
+// Printer 按选定格式输出区块信息
+type Printer interface {
+	// PrintBlock 输出一个区块
+	PrintBlock(info *BlockInfo) error
+	// PrintTotals 输出区间扫描的汇总，NDJSON 与 CSV 格式忽略汇总
+	PrintTotals(totals *Totals) error
+	// Flush 写出缓冲的内容，输出结束时必须调用
+	Flush() error
+}
+
+// PrinterOptions 控制输出内容
+type PrinterOptions struct {
+	// Verbose 仅影响文本格式：为 true 时逐字段多行打印，否则每个区块打印一行
+	Verbose bool
+	// Transactions 表示输出交易列表；CSV 格式下改为每笔交易输出一行
+	Transactions bool
+}
+
+// NewPrinter 创建指定格式的输出器
+func NewPrinter(format string, w io.Writer, opts PrinterOptions) (Printer, error) {
+	switch format {
+	case FormatHuman, "":
+		return &humanPrinter{w: w, opts: opts}, nil
+	case FormatJSON:
+		return &jsonPrinter{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonPrinter{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvPrinter{w: csv.NewWriter(w), txs: opts.Transactions}, nil
+	default:
+		return nil, fmt.Errorf("不支持的输出格式: %q", format)
+	}
+}
+
+// humanPrinter 输出人类可读的文本
+type humanPrinter struct {
+	w    io.Writer
+	opts PrinterOptions
+}
+
+func (p *humanPrinter) PrintBlock(info *BlockInfo) error {
+	if !p.opts.Verbose {
+		fmt.Fprintf(p.w, "区块 %d | 时间戳 %d | 哈希 %s | 交易数 %d | Gas %d/%d",
+			info.Number, info.Time, info.Hash.Hex(), info.TxCount, info.GasUsed, info.GasLimit)
+		if info.FailedTxs != nil {
+			fmt.Fprintf(p.w, " | 失败 %d", *info.FailedTxs)
+		}
+		fmt.Fprintln(p.w)
+		if info.Detail != nil {
+			if err := p.printDetail(info.Detail, "  "); err != nil {
+				return err
+			}
+		}
+		return p.printTransactions(info)
+	}
+	fmt.Fprintln(p.w, "区块编号:", info.Number)                            // 区块号
+	fmt.Fprintln(p.w, "区块时间戳:", info.Time)                             // 区块生成时间
+	fmt.Fprintln(p.w, "区块难度:", info.Difficulty)                        // 挖矿难度（通常为 0，因为使用 PoS）
+	fmt.Fprintln(p.w, "区块哈希:", info.Hash.Hex())                        // 区块哈希值
+	fmt.Fprintln(p.w, "父区块哈希:", info.ParentHash.Hex())                 // 父区块哈希值
+	fmt.Fprintln(p.w, "出块地址:", info.Miner.Hex())                       // 手续费接收地址
+	fmt.Fprintf(p.w, "Gas使用量: %d / %d\n", info.GasUsed, info.GasLimit) // Gas 使用量与上限
+	if info.BaseFee != nil {
+		fmt.Fprintf(p.w, "基础费用: %s gwei\n", units.FormatGwei(info.BaseFee)) // EIP-1559 基础费用
+	}
+	fmt.Fprintln(p.w, "交易数量:", info.TxCount) // 区块中包含的交易数
+	if info.FailedTxs != nil {
+		fmt.Fprintln(p.w, "失败交易数:", *info.FailedTxs) // 执行被回滚的交易数
+	}
+	if info.Detail != nil {
+		fmt.Fprintln(p.w, "---------- 区块头详情 ----------")
+		if err := p.printDetail(info.Detail, ""); err != nil {
+			return err
+		}
+	}
+	return p.printTransactions(info)
+}
+
+// printDetail 打印合并及之后升级引入的区块头字段，未激活的字段不打印
+func (p *humanPrinter) printDetail(d *HeaderDetail, indent string) error {
+	fmt.Fprintf(p.w, "%s状态根: %s\n", indent, d.StateRoot.Hex())
+	fmt.Fprintf(p.w, "%s交易根: %s\n", indent, d.TxRoot.Hex())
+	fmt.Fprintf(p.w, "%s回执根: %s\n", indent, d.ReceiptRoot.Hex())
+	fmt.Fprintf(p.w, "%sPrevRandao: %s\n", indent, d.PrevRandao.Hex())
+	fmt.Fprintf(p.w, "%s附加数据: %s\n", indent, d.ExtraData)
+	if d.WithdrawalsHash != nil {
+		fmt.Fprintf(p.w, "%s提款根: %s\n", indent, d.WithdrawalsHash.Hex())
+		fmt.Fprintf(p.w, "%s提款数量: %d\n", indent, len(d.Withdrawals))
+		for _, w := range d.Withdrawals {
+			fmt.Fprintf(p.w, "%s  提款 #%d 验证者 %d -> %s %d gwei\n", indent, w.Index, w.Validator, w.Address.Hex(), w.Amount)
+		}
+	}
+	if d.BlobGasUsed != nil {
+		fmt.Fprintf(p.w, "%sBlob Gas使用量: %d\n", indent, *d.BlobGasUsed)
+	}
+	if d.ExcessBlobGas != nil {
+		fmt.Fprintf(p.w, "%s超额Blob Gas: %d\n", indent, *d.ExcessBlobGas)
+	}
+	if d.BlobBaseFee != nil {
+		fmt.Fprintf(p.w, "%sBlob基础费用: %s gwei\n", indent, units.FormatGwei(d.BlobBaseFee))
+	}
+	if d.ParentBeaconRoot != nil {
+		fmt.Fprintf(p.w, "%s父信标区块根: %s\n", indent, d.ParentBeaconRoot.Hex())
+	}
+	if d.RequestsHash != nil {
+		_, err := fmt.Fprintf(p.w, "%s执行层请求哈希: %s\n", indent, d.RequestsHash.Hex())
+		return err
+	}
+	return nil
+}
+
+// printTransactions 逐笔打印交易，每笔交易占两行
+func (p *humanPrinter) printTransactions(info *BlockInfo) error {
+	for _, tx := range info.Transactions {
+		fmt.Fprintf(p.w, "  #%d %s [%s] %s -> %s\n",
+			tx.Index, tx.Hash.Hex(), tx.TypeName, tx.From.Hex(), tx.recipient())
+		_, err := fmt.Fprintf(p.w, "     金额 %s ETH | nonce %d | gas %d | %s | 选择器 %s | 数据 %d 字节\n",
+			units.FormatEther(tx.Value), tx.Nonce, tx.Gas, tx.fees(), orDash(tx.Selector), tx.InputSize)
+		if err != nil {
+			return err
+		}
+		if tx.Receipt != nil {
+			if err := p.printReceipt(tx.Receipt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printReceipt 打印交易的执行结果与事件日志
+func (p *humanPrinter) printReceipt(r *ReceiptInfo) error {
+	status := "✅ 成功"
+	if !r.Succeeded() {
+		status = "❌ 回滚"
+	}
+	fmt.Fprintf(p.w, "     %s | gasUsed %d | 有效Gas价格 %s gwei", status, r.GasUsed, units.FormatGwei(r.EffectiveGasPrice))
+	if r.ContractAddress != nil {
+		fmt.Fprintf(p.w, " | 新合约 %s", r.ContractAddress.Hex())
+	}
+	fmt.Fprintf(p.w, " | 日志 %d 条\n", len(r.Logs))
+	for _, l := range r.Logs {
+		fmt.Fprintf(p.w, "       日志 #%d %s\n", l.Index, l.Address.Hex())
+		for i, topic := range l.Topics {
+			fmt.Fprintf(p.w, "         topic[%d] %s\n", i, topic.Hex())
+		}
+		if _, err := fmt.Fprintf(p.w, "         data %s\n", l.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *humanPrinter) PrintTotals(t *Totals) error {
+	fmt.Fprintln(p.w, "========== 扫描汇总 ==========")
+	fmt.Fprintln(p.w, "区块数量:", t.Blocks)
+	fmt.Fprintln(p.w, "交易总数:", t.TxCount)
+	if t.FailedTxs != nil {
+		fmt.Fprintln(p.w, "失败交易数:", *t.FailedTxs)
+	}
+	fmt.Fprintln(p.w, "Gas使用总量:", t.GasUsed)
+	if t.Blocks > 0 {
+		fmt.Fprintf(p.w, "平均每块交易数: %.2f\n", float64(t.TxCount)/float64(t.Blocks))
+		fmt.Fprintf(p.w, "时间跨度: %s - %s (%s)\n",
+			time.Unix(int64(t.FirstTime), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(t.LastTime), 0).UTC().Format(time.RFC3339),
+			time.Duration(t.LastTime-t.FirstTime)*time.Second)
+	}
+	return nil
+}
+
+func (p *humanPrinter) Flush() error { return nil }
+
+// jsonPrinter 缓存全部区块，在 Flush 时输出一个 JSON 文档
+type jsonPrinter struct {
+	w      io.Writer
+	blocks []*BlockInfo
+	totals *Totals
+}
+
+func (p *jsonPrinter) PrintBlock(info *BlockInfo) error {
+	p.blocks = append(p.blocks, info)
+	return nil
+}
+
+func (p *jsonPrinter) PrintTotals(t *Totals) error {
+	p.totals = t
+	return nil
+}
+
+func (p *jsonPrinter) Flush() error {
+	doc := struct {
+		Blocks []*BlockInfo `json:"blocks"`
+		Totals *Totals      `json:"totals,omitempty"`
+	}{Blocks: p.blocks, Totals: p.totals}
+	if doc.Blocks == nil {
+		doc.Blocks = []*BlockInfo{}
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ndjsonPrinter 每个区块输出一行 JSON，适合流式处理
+type ndjsonPrinter struct {
+	enc *json.Encoder
+}
+
+func (p *ndjsonPrinter) PrintBlock(info *BlockInfo) error { return p.enc.Encode(info) }
+func (p *ndjsonPrinter) PrintTotals(*Totals) error        { return nil }
+func (p *ndjsonPrinter) Flush() error                     { return nil }
+
+// csvPrinter 每个区块（或每笔交易）输出一行 CSV，首行为表头
+type csvPrinter struct {
+	w           *csv.Writer
+	txs         bool
+	wroteHeader bool
+}
+
+var csvHeader = []string{
+	"number", "hash", "parentHash", "timestamp", "difficulty",
+	"gasUsed", "gasLimit", "baseFee", "miner", "txCount",
+	"withdrawalsRoot", "withdrawalCount", "blobGasUsed", "excessBlobGas", "blobBaseFee",
+	"parentBeaconRoot", "requestsHash",
+}
+
+var csvTxHeader = []string{
+	"blockNumber", "index", "hash", "type", "from", "to", "value", "nonce", "gas",
+	"gasPrice", "maxPriorityFeePerGas", "maxFeePerGas", "maxFeePerBlobGas", "blobCount",
+	"selector", "inputSize", "status", "gasUsed", "effectiveGasPrice", "contractAddress", "logCount",
+}
+
+func (p *csvPrinter) PrintBlock(info *BlockInfo) error {
+	if !p.wroteHeader {
+		header := csvHeader
+		if p.txs {
+			header = csvTxHeader
+		}
+		if err := p.w.Write(header); err != nil {
+			return err
+		}
+		p.wroteHeader = true
+	}
+	if p.txs {
+		return p.printTransactions(info)
+	}
+	// 详细区块头字段仅在请求时填充，否则留空
+	detail := make([]string, 7)
+	if d := info.Detail; d != nil {
+		detail = []string{
+			hashString(d.WithdrawalsHash),
+			strconv.Itoa(len(d.Withdrawals)),
+			uintString(d.BlobGasUsed),
+			uintString(d.ExcessBlobGas),
+			bigString(d.BlobBaseFee),
+			hashString(d.ParentBeaconRoot),
+			hashString(d.RequestsHash),
+		}
+	}
+	return p.w.Write(append([]string{
+		strconv.FormatUint(info.Number, 10),
+		info.Hash.Hex(),
+		info.ParentHash.Hex(),
+		strconv.FormatUint(info.Time, 10),
+		bigString(info.Difficulty),
+		strconv.FormatUint(info.GasUsed, 10),
+		strconv.FormatUint(info.GasLimit, 10),
+		bigString(info.BaseFee),
+		info.Miner.Hex(),
+		strconv.Itoa(info.TxCount),
+	}, detail...))
+}
+
+func (p *csvPrinter) printTransactions(info *BlockInfo) error {
+	for _, tx := range info.Transactions {
+		to := ""
+		if tx.To != nil {
+			to = tx.To.Hex()
+		}
+		var status, gasUsed, effectiveGasPrice, contractAddress, logCount string
+		if r := tx.Receipt; r != nil {
+			status = strconv.FormatUint(r.Status, 10)
+			gasUsed = strconv.FormatUint(r.GasUsed, 10)
+			effectiveGasPrice = bigString(r.EffectiveGasPrice)
+			if r.ContractAddress != nil {
+				contractAddress = r.ContractAddress.Hex()
+			}
+			logCount = strconv.Itoa(len(r.Logs))
+		}
+		err := p.w.Write([]string{
+			strconv.FormatUint(info.Number, 10),
+			strconv.Itoa(tx.Index),
+			tx.Hash.Hex(),
+			tx.TypeName,
+			tx.From.Hex(),
+			to,
+			bigString(tx.Value),
+			strconv.FormatUint(tx.Nonce, 10),
+			strconv.FormatUint(tx.Gas, 10),
+			bigString(tx.GasPrice),
+			bigString(tx.GasTipCap),
+			bigString(tx.GasFeeCap),
+			bigString(tx.BlobGasFeeCap),
+			strconv.Itoa(tx.BlobCount),
+			tx.Selector,
+			strconv.Itoa(tx.InputSize),
+			status,
+			gasUsed,
+			effectiveGasPrice,
+			contractAddress,
+			logCount,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *csvPrinter) PrintTotals(*Totals) error { return nil }
+
+func (p *csvPrinter) Flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
+
+// bigString 将可能为 nil 的大整数格式化为十进制字符串，nil 输出为空串
+func bigString(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// uintString 将可能为 nil 的整数格式化为十进制字符串，nil 输出为空串
+func uintString(v *uint64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+// hashString 将可能为 nil 的哈希格式化为十六进制字符串，nil 输出为空串
+func hashString(h *common.Hash) string {
+	if h == nil {
+		return ""
+	}
+	return h.Hex()
+}
+
+// orDash 将空字符串显示为 "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
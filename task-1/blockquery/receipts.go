@@ -0,0 +1,125 @@
+package blockquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ReceiptInfo 是输出用的交易回执信息
+type ReceiptInfo struct {
+	Status            uint64          `json:"status"` // 1 表示成功，0 表示回滚
+	GasUsed           uint64          `json:"gasUsed"`
+	EffectiveGasPrice *big.Int        `json:"effectiveGasPrice"`
+	BlobGasUsed       uint64          `json:"blobGasUsed,omitempty"`
+	ContractAddress   *common.Address `json:"contractAddress,omitempty"` // 仅合约创建交易
+	Logs              []*LogInfo      `json:"logs"`
+}
+
+// LogInfo 是输出用的事件日志
+type LogInfo struct {
+	Index   uint           `json:"logIndex"`
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// Succeeded 判断交易是否执行成功
+func (r *ReceiptInfo) Succeeded() bool {
+	return r.Status == types.ReceiptStatusSuccessful
+}
+
+// NewReceiptInfo 从回执中提取输出信息
+func NewReceiptInfo(receipt *types.Receipt) *ReceiptInfo {
+	info := &ReceiptInfo{
+		Status:            receipt.Status,
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: receipt.EffectiveGasPrice,
+		BlobGasUsed:       receipt.BlobGasUsed,
+		Logs:              make([]*LogInfo, len(receipt.Logs)),
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		addr := receipt.ContractAddress
+		info.ContractAddress = &addr
+	}
+	for i, l := range receipt.Logs {
+		info.Logs[i] = &LogInfo{
+			Index:   l.Index,
+			Address: l.Address,
+			Topics:  l.Topics,
+			Data:    l.Data,
+		}
+	}
+	return info
+}
+
+// FetchReceipts 获取区块内全部交易的回执，结果与区块交易顺序一致。
+// 优先使用一次 eth_getBlockReceipts 调用，节点不支持或结果不完整时
+// 回退为以不超过 concurrency 的并发度逐笔调用 eth_getTransactionReceipt
+func FetchReceipts(ctx context.Context, backend Backend, block *types.Block, concurrency int) ([]*types.Receipt, error) {
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	receipts, err := backend.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+	if err == nil && len(receipts) == len(txs) {
+		return receipts, nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		sem      = make(chan struct{}, concurrency)
+		errOnce  sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	receipts = make([]*types.Receipt, len(txs))
+	for i, tx := range txs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, hash common.Hash) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			receipt, err := backend.TransactionReceipt(ctx, hash)
+			if err != nil {
+				errOnce.Do(func() { firstErr = fmt.Errorf("获取交易 %s 的回执失败: %w", hash.Hex(), err) })
+				return
+			}
+			receipts[i] = receipt
+		}(i, tx.Hash())
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return receipts, nil
+}
+
+// AttachReceipts 将回执按交易哈希关联到交易信息上，并返回执行失败的交易数
+func AttachReceipts(txs []*TxInfo, receipts []*types.Receipt) (int, error) {
+	byHash := make(map[common.Hash]*types.Receipt, len(receipts))
+	for _, receipt := range receipts {
+		byHash[receipt.TxHash] = receipt
+	}
+	failed := 0
+	for _, tx := range txs {
+		receipt, ok := byHash[tx.Hash]
+		if !ok {
+			return 0, fmt.Errorf("缺少交易 %s 的回执", tx.Hash.Hex())
+		}
+		tx.Receipt = NewReceiptInfo(receipt)
+		if !tx.Receipt.Succeeded() {
+			failed++
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,136 @@
+package blockquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Backend 是区块查询所需的最小节点接口，由节点客户端或包装它的磁盘缓存 Cache 实现
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error)
+	BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Totals 是一次区间扫描的汇总数据
+type Totals struct {
+	Blocks    int    `json:"blocks"`              // 扫描的区块数
+	TxCount   int    `json:"txCount"`             // 交易总数
+	FailedTxs *int   `json:"failedTxs,omitempty"` // 执行失败的交易数，仅在获取回执时统计
+	GasUsed   uint64 `json:"gasUsed"`             // Gas 使用总量
+	FirstTime uint64 `json:"firstTimestamp"`      // 第一个区块的时间戳
+	LastTime  uint64 `json:"lastTimestamp"`       // 最后一个区块的时间戳
+}
+
+// Add 将一个区块计入汇总
+func (t *Totals) Add(info *BlockInfo) {
+	if t.Blocks == 0 || info.Time < t.FirstTime {
+		t.FirstTime = info.Time
+	}
+	if info.Time > t.LastTime {
+		t.LastTime = info.Time
+	}
+	t.Blocks++
+	t.TxCount += info.TxCount
+	if info.FailedTxs != nil {
+		if t.FailedTxs == nil {
+			t.FailedTxs = new(int)
+		}
+		*t.FailedTxs += *info.FailedTxs
+	}
+	t.GasUsed += info.GasUsed
+}
+
+// LatestRange 返回以最新区块结尾、长度为 n 的区块区间
+func LatestRange(ctx context.Context, backend Backend, n uint64) (uint64, uint64, error) {
+	if n == 0 {
+		return 0, 0, fmt.Errorf("区块数量必须大于 0")
+	}
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("获取最新区块头失败: %w", err)
+	}
+	end := head.Number.Uint64()
+	if n > end+1 {
+		n = end + 1
+	}
+	return end - n + 1, end, nil
+}
+
+// ScanRange 以不超过 concurrency 的并发度获取 [start, end] 区间内的全部区块，
+// 并按区块号升序对每个区块调用 fn。已获取但尚未处理完的区块与在途请求合计不超过 concurrency 个，
+// fn 处理较慢时获取随之暂停，内存占用与区间长度无关
+func ScanRange(ctx context.Context, backend Backend, start, end uint64, concurrency int, fn func(*types.Block) error) error {
+	if start > end {
+		return fmt.Errorf("起始区块 %d 大于结束区块 %d", start, end)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		count  = end - start + 1
+		size   = uint64(concurrency)
+		window = make([]chan *types.Block, size) // 滑动窗口，区块 start+i 放在第 i%size 个槽位
+		slots  = make(chan struct{}, size)       // 占用的槽位，区块被 fn 处理完后才归还
+		errs   = make(chan error, 1)
+	)
+	for i := range window {
+		window[i] = make(chan *types.Block, 1)
+	}
+
+	// 生产者：按顺序派发任务，没有空闲槽位时等待消费者处理完最早的区块。
+	// 占用槽位的区块号都在最早未处理的区块之后 size 个以内，因此槽位不会被重复使用
+	go func() {
+		for i := uint64(0); i < count; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i uint64) {
+				number := new(big.Int).SetUint64(start + i)
+				block, err := backend.BlockByNumber(ctx, number)
+				if err != nil {
+					select {
+					case errs <- fmt.Errorf("获取区块 %d 失败: %w", start+i, err):
+					default:
+					}
+					cancel()
+					return
+				}
+				window[i%size] <- block
+			}(i)
+		}
+	}()
+
+	// 消费者：按区块号顺序输出，保证结果有序
+	for i := uint64(0); i < count; i++ {
+		select {
+		case block := <-window[i%size]:
+			if err := fn(block); err != nil {
+				return err
+			}
+			<-slots
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			select {
+			case err := <-errs:
+				return err
+			default:
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package blockquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 区块标签与 rpc 包中特殊区块号的对应关系
+var blockTags = map[string]rpc.BlockNumber{
+	"latest":    rpc.LatestBlockNumber,
+	"safe":      rpc.SafeBlockNumber,
+	"finalized": rpc.FinalizedBlockNumber,
+	"pending":   rpc.PendingBlockNumber,
+	"earliest":  rpc.EarliestBlockNumber,
+}
+
+// BlockRef 描述一个区块引用：按哈希，或按区块号/标签
+type BlockRef struct {
+	Hash   *common.Hash    // 非 nil 时按哈希查找
+	Number rpc.BlockNumber // 区块号，负数表示标签
+}
+
+// String 返回区块引用的可读形式
+func (r BlockRef) String() string {
+	if r.Hash != nil {
+		return r.Hash.Hex()
+	}
+	return r.Number.String()
+}
+
+// ParseBlockRef 解析区块引用，支持十进制或 0x 前缀的区块号、
+// 32 字节的区块哈希，以及 latest、safe、finalized、pending、earliest 标签
+func ParseBlockRef(s string) (BlockRef, error) {
+	s = strings.TrimSpace(s)
+	if number, ok := blockTags[strings.ToLower(s)]; ok {
+		return BlockRef{Number: number}, nil
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if len(s) == 2+2*common.HashLength {
+			hash := common.HexToHash(s)
+			return BlockRef{Hash: &hash}, nil
+		}
+		n, err := strconv.ParseUint(s[2:], 16, 63)
+		if err != nil {
+			return BlockRef{}, fmt.Errorf("无效的区块号或哈希: %q", s)
+		}
+		return BlockRef{Number: rpc.BlockNumber(n)}, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 63)
+	if err != nil {
+		return BlockRef{}, fmt.Errorf("无效的区块引用: %q", s)
+	}
+	return BlockRef{Number: rpc.BlockNumber(n)}, nil
+}
+
+// BlockByRef 获取区块引用指向的完整区块
+func BlockByRef(ctx context.Context, backend Backend, ref BlockRef) (*types.Block, error) {
+	if ref.Hash != nil {
+		return backend.BlockByHash(ctx, *ref.Hash)
+	}
+	return backend.BlockByNumber(ctx, big.NewInt(ref.Number.Int64()))
+}
+
+// ParseTime 解析时间，支持 Unix 秒级时间戳、RFC 3339 格式，
+// 以及不带时区（按 UTC 处理）的 "2006-01-02 15:04:05"、"2006-01-02T15:04" 和 "2006-01-02"
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %q", s)
+}
+
+// HeaderAtTime 通过对区块头的二分查找，返回在给定时刻为最新的区块，
+// 即时间戳不晚于 t 的最后一个区块
+func HeaderAtTime(ctx context.Context, backend Backend, t time.Time) (*types.Header, error) {
+	target := t.Unix()
+	if target < 0 {
+		return nil, fmt.Errorf("时间 %s 早于 Unix 纪元", t)
+	}
+	ts := uint64(target)
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新区块头失败: %w", err)
+	}
+	if head.Time <= ts {
+		return head, nil
+	}
+	genesis, err := backend.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		return nil, fmt.Errorf("获取创世区块头失败: %w", err)
+	}
+	if genesis.Time > ts {
+		return nil, fmt.Errorf("时间 %s 早于创世区块", t.UTC().Format(time.RFC3339))
+	}
+
+	// 不变量: lo.Time <= ts < hi.Time
+	lo, hi := genesis, head
+	for hi.Number.Uint64()-lo.Number.Uint64() > 1 {
+		mid := (lo.Number.Uint64() + hi.Number.Uint64()) / 2
+		header, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, fmt.Errorf("获取区块头 %d 失败: %w", mid, err)
+		}
+		if header.Time <= ts {
+			lo = header
+		} else {
+			hi = header
+		}
+	}
+	return lo, nil
+}
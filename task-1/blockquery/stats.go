@@ -0,0 +1,300 @@
+package blockquery
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"practical-task/units"
+)
+
+// 统计报告中输出的优先费百分位
+var tipPercentiles = []int{10, 25, 50, 75, 90, 99}
+
+// Stats 是一个区块区间的统计报告
+type Stats struct {
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+	Blocks    int    `json:"blocks"`
+	TxCount   int    `json:"txCount"`
+
+	GasUsedRatio GasUsedRatioStats `json:"gasUsedRatio"`
+	BaseFee      BaseFeeStats      `json:"baseFee"`
+	PriorityFee  []Percentile      `json:"priorityFeePercentiles"` // 单位 wei，按交易统计
+	TxTypes      map[string]int    `json:"txTypes"`
+	Blobs        BlobStats         `json:"blobs"`
+	TopSenders   []AddressValue    `json:"topSenders"`   // 按转出金额排序
+	TopReceivers []AddressValue    `json:"topReceivers"` // 按收到金额排序
+}
+
+// GasUsedRatioStats 是区块 Gas 使用率（gasUsed / gasLimit）的统计
+type GasUsedRatioStats struct {
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+}
+
+// BaseFeeStats 是基础费用的变化趋势，单位 wei
+type BaseFeeStats struct {
+	First     *big.Int       `json:"first"`
+	Last      *big.Int       `json:"last"`
+	Min       *big.Int       `json:"min"`
+	Max       *big.Int       `json:"max"`
+	Average   *big.Int       `json:"average"`
+	ChangePct float64        `json:"changePct"` // 从第一个区块到最后一个区块的变化百分比
+	Series    []BaseFeePoint `json:"series"`
+}
+
+// BaseFeePoint 是基础费用序列中的一个点
+type BaseFeePoint struct {
+	Number  uint64   `json:"number"`
+	BaseFee *big.Int `json:"baseFee"`
+}
+
+// Percentile 是一个百分位数值
+type Percentile struct {
+	Percentile int      `json:"percentile"`
+	Value      *big.Int `json:"value"`
+}
+
+// BlobStats 是 EIP-4844 blob 的使用情况
+type BlobStats struct {
+	BlobTxs          int     `json:"blobTxs"`
+	Blobs            int     `json:"blobs"`
+	BlobGasUsed      uint64  `json:"blobGasUsed"`
+	BlocksWithBlobs  int     `json:"blocksWithBlobs"`
+	AvgBlobsPerBlock float64 `json:"avgBlobsPerBlock"`
+}
+
+// AddressValue 是某个地址的累计转账金额
+type AddressValue struct {
+	Address common.Address `json:"address"`
+	Value   *big.Int       `json:"value"` // 单位 wei
+	TxCount int            `json:"txCount"`
+}
+
+// StatsCollector 逐块累积统计数据
+type StatsCollector struct {
+	top int // 输出的发送方/接收方数量
+
+	stats      Stats
+	ratios     []float64
+	baseFeeSum *big.Int
+	tips       []*big.Int
+	senders    map[common.Address]*AddressValue
+	receivers  map[common.Address]*AddressValue
+}
+
+// NewStatsCollector 创建统计收集器，top 为排行榜的长度
+func NewStatsCollector(top int) *StatsCollector {
+	return &StatsCollector{
+		top:        top,
+		stats:      Stats{TxTypes: make(map[string]int)},
+		baseFeeSum: new(big.Int),
+		senders:    make(map[common.Address]*AddressValue),
+		receivers:  make(map[common.Address]*AddressValue),
+	}
+}
+
+// Add 将一个区块计入统计，区块需按区块号升序加入
+func (c *StatsCollector) Add(block *types.Block) error {
+	header := block.Header()
+	number := header.Number.Uint64()
+	if c.stats.Blocks == 0 {
+		c.stats.FromBlock = number
+	}
+	c.stats.ToBlock = number
+	c.stats.Blocks++
+	c.stats.TxCount += len(block.Transactions())
+
+	if header.GasLimit > 0 {
+		c.ratios = append(c.ratios, float64(header.GasUsed)/float64(header.GasLimit))
+	}
+	if baseFee := header.BaseFee; baseFee != nil {
+		bf := &c.stats.BaseFee
+		if bf.First == nil {
+			bf.First = baseFee
+		}
+		bf.Last = baseFee
+		if bf.Min == nil || baseFee.Cmp(bf.Min) < 0 {
+			bf.Min = baseFee
+		}
+		if bf.Max == nil || baseFee.Cmp(bf.Max) > 0 {
+			bf.Max = baseFee
+		}
+		c.baseFeeSum.Add(c.baseFeeSum, baseFee)
+		bf.Series = append(bf.Series, BaseFeePoint{Number: number, BaseFee: baseFee})
+	}
+	if header.BlobGasUsed != nil {
+		c.stats.Blobs.BlobGasUsed += *header.BlobGasUsed
+	}
+
+	blobs := 0
+	for _, tx := range block.Transactions() {
+		c.stats.TxTypes[TxTypeName(tx.Type())]++
+		if tx.Type() == types.BlobTxType {
+			c.stats.Blobs.BlobTxs++
+			blobs += len(tx.BlobHashes())
+		}
+		if tip, err := tx.EffectiveGasTip(header.BaseFee); err == nil {
+			c.tips = append(c.tips, tip)
+		}
+
+		from, err := types.Sender(SignerFor(tx), tx)
+		if err != nil {
+			return fmt.Errorf("恢复交易 %s 的发送方失败: %w", tx.Hash().Hex(), err)
+		}
+		addValue(c.senders, from, tx.Value())
+		if to := tx.To(); to != nil {
+			addValue(c.receivers, *to, tx.Value())
+		}
+	}
+	c.stats.Blobs.Blobs += blobs
+	if blobs > 0 {
+		c.stats.Blobs.BlocksWithBlobs++
+	}
+	return nil
+}
+
+// Result 计算并返回统计报告
+func (c *StatsCollector) Result() *Stats {
+	s := c.stats
+	if n := len(c.ratios); n > 0 {
+		sorted := append([]float64(nil), c.ratios...)
+		sort.Float64s(sorted)
+		sum := 0.0
+		for _, r := range sorted {
+			sum += r
+		}
+		s.GasUsedRatio.Average = sum / float64(n)
+		if n%2 == 1 {
+			s.GasUsedRatio.Median = sorted[n/2]
+		} else {
+			s.GasUsedRatio.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+		}
+	}
+	if points := len(s.BaseFee.Series); points > 0 {
+		s.BaseFee.Average = new(big.Int).Div(c.baseFeeSum, big.NewInt(int64(points)))
+		if s.BaseFee.First.Sign() > 0 {
+			first, _ := new(big.Float).SetInt(s.BaseFee.First).Float64()
+			last, _ := new(big.Float).SetInt(s.BaseFee.Last).Float64()
+			s.BaseFee.ChangePct = (last - first) / first * 100
+		}
+	}
+	if len(c.tips) > 0 {
+		sorted := append([]*big.Int(nil), c.tips...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+		for _, p := range tipPercentiles {
+			// 最近秩法：第 ceil(p/100 * n) 个值
+			rank := (p*len(sorted) + 99) / 100
+			if rank < 1 {
+				rank = 1
+			}
+			s.PriorityFee = append(s.PriorityFee, Percentile{Percentile: p, Value: sorted[rank-1]})
+		}
+	}
+	if s.Blocks > 0 {
+		s.Blobs.AvgBlobsPerBlock = float64(s.Blobs.Blobs) / float64(s.Blocks)
+	}
+	s.TopSenders = topByValue(c.senders, c.top)
+	s.TopReceivers = topByValue(c.receivers, c.top)
+	return &s
+}
+
+// WriteStatsTable 以表格形式输出统计报告
+func WriteStatsTable(w io.Writer, s *Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "========== 区块统计 %d - %d ==========\n", s.FromBlock, s.ToBlock)
+	fmt.Fprintf(tw, "区块数量\t%d\n", s.Blocks)
+	fmt.Fprintf(tw, "交易总数\t%d\n", s.TxCount)
+	fmt.Fprintf(tw, "Gas使用率 平均/中位数\t%.2f%% / %.2f%%\n", s.GasUsedRatio.Average*100, s.GasUsedRatio.Median*100)
+	if s.BaseFee.First != nil {
+		fmt.Fprintf(tw, "基础费用 首/末 (gwei)\t%s / %s (%+.2f%%)\n",
+			units.FormatGwei(s.BaseFee.First), units.FormatGwei(s.BaseFee.Last), s.BaseFee.ChangePct)
+		fmt.Fprintf(tw, "基础费用 最低/平均/最高 (gwei)\t%s / %s / %s\n",
+			units.FormatGwei(s.BaseFee.Min), units.FormatGwei(s.BaseFee.Average), units.FormatGwei(s.BaseFee.Max))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n---------- 优先费百分位 (gwei) ----------")
+	for _, p := range s.PriorityFee {
+		fmt.Fprintf(tw, "P%d\t%s\n", p.Percentile, units.FormatGwei(p.Value))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n---------- 交易类型分布 ----------")
+	names := make([]string, 0, len(s.TxTypes))
+	for name := range s.TxTypes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := s.TxTypes[names[i]], s.TxTypes[names[j]]; a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		share := 0.0
+		if s.TxCount > 0 {
+			share = float64(s.TxTypes[name]) / float64(s.TxCount) * 100
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f%%\n", name, s.TxTypes[name], share)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n---------- Blob 使用情况 ----------")
+	fmt.Fprintf(tw, "Blob交易数\t%d\n", s.Blobs.BlobTxs)
+	fmt.Fprintf(tw, "Blob总数\t%d\n", s.Blobs.Blobs)
+	fmt.Fprintf(tw, "Blob Gas使用总量\t%d\n", s.Blobs.BlobGasUsed)
+	fmt.Fprintf(tw, "含Blob的区块数\t%d\n", s.Blobs.BlocksWithBlobs)
+	fmt.Fprintf(tw, "平均每块Blob数\t%.2f\n", s.Blobs.AvgBlobsPerBlock)
+	tw.Flush()
+
+	writeAddressTable(w, tw, "转出金额最多的发送方", s.TopSenders)
+	writeAddressTable(w, tw, "收到金额最多的接收方", s.TopReceivers)
+	return tw.Flush()
+}
+
+func writeAddressTable(w io.Writer, tw *tabwriter.Writer, title string, rows []AddressValue) {
+	fmt.Fprintf(w, "\n---------- %s ----------\n", title)
+	fmt.Fprintln(tw, "地址\t金额 (ETH)\t交易数")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", row.Address.Hex(), units.FormatEther(row.Value), row.TxCount)
+	}
+	tw.Flush()
+}
+
+func addValue(m map[common.Address]*AddressValue, addr common.Address, value *big.Int) {
+	entry, ok := m[addr]
+	if !ok {
+		entry = &AddressValue{Address: addr, Value: new(big.Int)}
+		m[addr] = entry
+	}
+	entry.Value.Add(entry.Value, value)
+	entry.TxCount++
+}
+
+// topByValue 返回金额最大的 n 个地址，金额相同时按交易数排序，再相同时按地址排序，保证报告可复现
+func topByValue(m map[common.Address]*AddressValue, n int) []AddressValue {
+	list := make([]AddressValue, 0, len(m))
+	for _, entry := range m {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if c := list[i].Value.Cmp(list[j].Value); c != 0 {
+			return c > 0
+		}
+		if list[i].TxCount != list[j].TxCount {
+			return list[i].TxCount > list[j].TxCount
+		}
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,140 @@
+package blockquery
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"practical-task/units"
+)
+
+// TxInfo 是输出用的交易信息
+type TxInfo struct {
+	Index         int             `json:"index"`
+	Hash          common.Hash     `json:"hash"`
+	Type          uint8           `json:"type"`
+	TypeName      string          `json:"typeName"`
+	From          common.Address  `json:"from"`
+	To            *common.Address `json:"to"` // nil 表示合约创建
+	Value         *big.Int        `json:"value"`
+	Nonce         uint64          `json:"nonce"`
+	Gas           uint64          `json:"gas"`
+	GasPrice      *big.Int        `json:"gasPrice,omitempty"`             // 仅 legacy 与 access-list 交易
+	GasTipCap     *big.Int        `json:"maxPriorityFeePerGas,omitempty"` // EIP-1559 及之后的交易
+	GasFeeCap     *big.Int        `json:"maxFeePerGas,omitempty"`         // EIP-1559 及之后的交易
+	BlobGasFeeCap *big.Int        `json:"maxFeePerBlobGas,omitempty"`     // 仅 blob 交易
+	BlobCount     int             `json:"blobCount,omitempty"`            // 仅 blob 交易
+	Selector      string          `json:"selector,omitempty"`             // 调用数据的前 4 个字节
+	InputSize     int             `json:"inputSize"`
+
+	Receipt *ReceiptInfo `json:"receipt,omitempty"` // 仅在请求回执时填充
+}
+
+// TxTypeName 返回交易类型的可读名称
+func TxTypeName(txType uint8) string {
+	switch txType {
+	case types.LegacyTxType:
+		return "legacy"
+	case types.AccessListTxType:
+		return "access-list"
+	case types.DynamicFeeTxType:
+		return "dynamic-fee"
+	case types.BlobTxType:
+		return "blob"
+	case types.SetCodeTxType:
+		return "set-code"
+	default:
+		return fmt.Sprintf("unknown(%d)", txType)
+	}
+}
+
+// SignerFor 返回与交易类型相匹配的签名器，用于恢复发送方地址。
+// 未启用 EIP-155 重放保护的旧交易只能使用 Homestead 签名器恢复
+func SignerFor(tx *types.Transaction) types.Signer {
+	switch tx.Type() {
+	case types.LegacyTxType:
+		if !tx.Protected() {
+			return types.HomesteadSigner{}
+		}
+		return types.NewEIP155Signer(tx.ChainId())
+	case types.AccessListTxType:
+		return types.NewEIP2930Signer(tx.ChainId())
+	case types.DynamicFeeTxType:
+		return types.NewLondonSigner(tx.ChainId())
+	case types.BlobTxType:
+		return types.NewCancunSigner(tx.ChainId())
+	default:
+		return types.LatestSignerForChainID(tx.ChainId())
+	}
+}
+
+// NewTxInfo 解析区块中第 index 笔交易，恢复其发送方
+func NewTxInfo(tx *types.Transaction, index int) (*TxInfo, error) {
+	from, err := types.Sender(SignerFor(tx), tx)
+	if err != nil {
+		return nil, fmt.Errorf("恢复交易 %s 的发送方失败: %w", tx.Hash().Hex(), err)
+	}
+	info := &TxInfo{
+		Index:     index,
+		Hash:      tx.Hash(),
+		Type:      tx.Type(),
+		TypeName:  TxTypeName(tx.Type()),
+		From:      from,
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Nonce:     tx.Nonce(),
+		Gas:       tx.Gas(),
+		InputSize: len(tx.Data()),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		info.GasPrice = tx.GasPrice()
+	default:
+		info.GasTipCap = tx.GasTipCap()
+		info.GasFeeCap = tx.GasFeeCap()
+	}
+	if tx.Type() == types.BlobTxType {
+		info.BlobGasFeeCap = tx.BlobGasFeeCap()
+		info.BlobCount = len(tx.BlobHashes())
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		info.Selector = hexutil.Encode(data[:4])
+	}
+	return info, nil
+}
+
+// NewTxInfos 解析区块中的全部交易
+func NewTxInfos(block *types.Block) ([]*TxInfo, error) {
+	txs := block.Transactions()
+	infos := make([]*TxInfo, len(txs))
+	for i, tx := range txs {
+		info, err := NewTxInfo(tx, i)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+	return infos, nil
+}
+
+// recipient 返回交易接收方的可读形式
+func (t *TxInfo) recipient() string {
+	if t.To == nil {
+		return "(合约创建)"
+	}
+	return t.To.Hex()
+}
+
+// fees 返回交易手续费字段的可读形式
+func (t *TxInfo) fees() string {
+	if t.GasPrice != nil {
+		return fmt.Sprintf("gasPrice %s gwei", units.FormatGwei(t.GasPrice))
+	}
+	s := fmt.Sprintf("tip %s gwei, feeCap %s gwei", units.FormatGwei(t.GasTipCap), units.FormatGwei(t.GasFeeCap))
+	if t.BlobGasFeeCap != nil {
+		s += fmt.Sprintf(", blobFeeCap %s gwei, blobs %d", units.FormatGwei(t.BlobGasFeeCap), t.BlobCount)
+	}
+	return s
+}
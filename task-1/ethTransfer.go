@@ -2,116 +2,1388 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"practical-task/config"
+	"practical-task/keysource"
+	"practical-task/nonce"
+	"practical-task/task-1/batch"
+	"practical-task/txutil"
+	"practical-task/units"
 )
 
 func main() {
-	// 连接到以太坊Sepolia测试网络
-	url := ""
-	fmt.Println("正在连接以太坊Sepolia测试网络...")
+	// 第一个参数不是选项时作为子命令，默认发送转账
+	cmd, args := "send", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "send":
+		runSend(args)
+	case "batch":
+		runBatch(args)
+	case "speedup":
+		runReplace(cmd, args, false)
+	case "cancel":
+		runReplace(cmd, args, true)
+	case "build":
+		runBuild(args)
+	case "sign":
+		runSign(args)
+	case "broadcast":
+		runBroadcast(args)
+	case "delegate":
+		runDelegate(args)
+	case "delegation":
+		runDelegation(args)
+	default:
+		log.Fatalf("❌ 未知命令 %q，可用命令: send、batch、speedup、cancel、build、sign、broadcast、delegate、delegation", cmd)
+	}
+}
+
+// networkFlags 是选择网络配置的命令行参数
+type networkFlags struct {
+	configPath         *string
+	networkName        *string
+	allowChainMismatch *bool
+}
+
+func addNetworkFlags(fs *flag.FlagSet) *networkFlags {
+	return &networkFlags{
+		configPath:         fs.String("config", "", "配置文件路径（默认读取 $DAPP_CONFIG 或 ./config.json）"),
+		networkName:        fs.String("network", "", "使用的网络配置名（默认读取 $DAPP_NETWORK 或配置中的 defaultNetwork）"),
+		allowChainMismatch: fs.Bool("allow-chain-mismatch", false, "链ID与网络配置不一致时仍然签名并发送（危险）"),
+	}
+}
+
+// keyFlags 是选择签名私钥的命令行参数
+type keyFlags struct {
+	account *string
+	keySpec *string
+}
+
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	return &keyFlags{
+		account: fs.String("account", "", "使用的账户名，对应配置中 accounts 的密钥来源"),
+		keySpec: fs.String("key", "", "私钥来源，优先于 -account: keystore:<路径>、env:<变量名> 或 file:<路径>"),
+	}
+}
+
+// waitFlags 是等待交易确认的命令行参数
+type waitFlags struct {
+	confirmations *uint64
+	timeout       *time.Duration
+}
+
+func addWaitFlags(fs *flag.FlagSet) *waitFlags {
+	return &waitFlags{
+		confirmations: fs.Uint64("confirmations", 1, "等待的确认数，0 表示发送后不等待"),
+		timeout:       fs.Duration("timeout", 5*time.Minute, "等待确认的超时时间"),
+	}
+}
+
+// commonFlags 是签名并发送交易的子命令共用的命令行参数
+type commonFlags struct {
+	*networkFlags
+	*keyFlags
+	*waitFlags
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	return &commonFlags{addNetworkFlags(fs), addKeyFlags(fs), addWaitFlags(fs)}
+}
+
+// session 是连接节点并核对链ID之后的运行环境，需要签名的子命令还会加载私钥
+type session struct {
+	network *config.Network
+	client  *ethclient.Client
+	key     *keysource.Key // 不签名的子命令为 nil
+	from    common.Address // 交易的发送方
+	chainID *big.Int
+}
+
+// open 读取配置、连接节点、核对链ID并加载私钥，任何一步失败都会退出
+func (f *commonFlags) open() *session {
+	cfg, s := f.connect()
+	s.key = f.load(cfg)
+	s.from = s.key.Address
+	fmt.Printf("📬 发送方地址: %s\n", s.from.Hex())
+	return s
+}
+
+// loadConfig 读取配置文件与选定的网络配置，失败时退出
+func (f *networkFlags) loadConfig() (*config.Config, *config.Network) {
+	cfg, network, err := config.LoadNetwork(*f.configPath, *f.networkName)
+	if err != nil {
+		log.Fatal("❌ 读取配置失败:", err)
+	}
+	return cfg, network
+}
+
+// connect 读取配置、连接节点并核对链ID，任何一步失败都会退出
+func (f *networkFlags) connect() (*config.Config, *session) {
+	// 读取网络配置
+	cfg, network := f.loadConfig()
+	url, err := network.Endpoint()
+	if err != nil {
+		log.Fatal("❌ 读取配置失败:", err)
+	}
+
+	// 连接到以太坊网络
+	fmt.Printf("正在连接以太坊网络 %s...\n", network.Name)
 	client, err := ethclient.Dial(url)
 	if err != nil {
 		log.Fatal("❌ 连接以太坊网络失败:", err)
 	}
 	fmt.Println("✅ 网络连接成功")
 
-	// 从私钥获取ECDSA私钥对象
-	fmt.Println("正在解析私钥...")
-	privateKey, err := crypto.HexToECDSA("")
+	// 获取网络链ID并与配置中的预期链ID核对，避免把交易签到错误的链上
+	fmt.Println("正在获取网络链ID...")
+	chainID, err := txutil.VerifyChainID(context.Background(), client, network.ExpectedChainID())
 	if err != nil {
-		log.Fatal("❌ 解析私钥失败:", err)
+		if chainID == nil {
+			log.Fatal("❌ 获取链ID失败:", err)
+		}
+		f.chainMismatch(err)
 	}
-	fmt.Println("✅ 私钥解析成功")
+	fmt.Printf("✅ 链ID获取成功: %s\n", chainID.String())
+
+	return cfg, &session{network: network, client: client, chainID: chainID}
+}
 
-	// 从私钥获取公钥
-	fmt.Println("正在从私钥提取公钥...")
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("❌ 公钥类型断言失败: publicKey不是*ecdsa.PublicKey类型")
+// chainMismatch 处理链ID校验失败：未指定 -allow-chain-mismatch 时退出
+func (f *networkFlags) chainMismatch(err error) {
+	if !*f.allowChainMismatch {
+		log.Fatal("❌ 链ID校验失败: ", err, "（确认无误后可使用 -allow-chain-mismatch 跳过校验）")
 	}
-	fmt.Println("✅ 公钥提取成功")
+	fmt.Printf("⚠️ 链ID校验失败，已按 -allow-chain-mismatch 继续: %v\n", err)
+}
 
-	// 从公钥获取发送方地址
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	fmt.Printf("📬 发送方地址: %s\n", fromAddress.Hex())
+// load 确定密钥来源并加载私钥，失败时退出
+func (f *keyFlags) load(cfg *config.Config) *keysource.Key {
+	// 确定密钥来源：-key 优先，其次是配置中的账户，最后是默认的环境变量
+	spec := *f.keySpec
+	if spec == "" {
+		var err error
+		if spec, err = cfg.Account(*f.account); err != nil {
+			log.Fatal("❌ 读取账户配置失败:", err)
+		}
+	}
+	if spec == "" {
+		spec = keysource.DefaultSpec
+	}
 
-	// 获取发送方地址的nonce值（交易序号）
-	fmt.Printf("正在获取地址 %s 的nonce值...\n", fromAddress.Hex())
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 从密钥来源加载私钥（keystore 文件、环境变量或权限受限的文件）
+	fmt.Println("正在加载私钥...")
+	key, err := keysource.Load(spec)
 	if err != nil {
-		log.Fatal("❌ 获取nonce失败:", err)
+		log.Fatal("❌ 加载私钥失败:", err)
 	}
-	fmt.Printf("✅ nonce值获取成功: %d\n", nonce)
+	fmt.Printf("✅ 私钥加载成功（来源: %s）\n", key.Source)
+	return key
+}
 
-	// 设置转账金额（单位：wei，这里是0.001 ETH）
-	value := big.NewInt(1e15) // in wei (0.001 eth)
-	fmt.Printf("💸 转账金额: %s wei (0.001 ETH)\n", value.String())
+// runSend 发送一笔转账交易
+func runSend(args []string) {
+	// 命令行参数
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	cf := addCommonFlags(fs)
+	gf := addGasFlags(fs)
+	sf := addSimFlags(fs)
+	to := fs.String("to", "0x56161e6389eD71C3D4a3C60a3a0a1C17D77Ef031", "接收方地址")
+	amount := fs.String("amount", "0.001", "转账金额，可带单位后缀，如 0.001、0.5 ether、20 gwei、1000 wei（默认单位 ether）")
+	dataHex := fs.String("data", "", "交易数据（十六进制），接收方为合约时使用")
+	accessListSpec := addAccessListFlag(fs)
+	fs.Parse(args)
 
-	// 设置Gas限制
-	gasLimit := uint64(21000) // in units
-	fmt.Printf("⛽ Gas限制: %d\n", gasLimit)
+	toAddress, value := parseTransfer(*to, *amount)
+	data := parseData(*dataHex)
 
-	// 获取建议的Gas价格
-	fmt.Println("正在获取建议的Gas价格...")
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	s := cf.open()
+	client, network := s.client, s.network
+	gf.apply(network)
+
+	// 获取发送方地址
+	fromAddress := s.key.Address
+
+	// 打印转账金额与接收方地址
+	fmt.Printf("💸 转账金额: %s ETH (%s wei)\n", units.FormatEther(value), value.String())
+	fmt.Printf("📧 接收方地址: %s\n", toAddress.Hex())
+
+	// 打印交易数据
+	if len(data) == 0 {
+		fmt.Printf("📄 交易数据: (空数据)\n")
+	} else {
+		fmt.Printf("📄 交易数据: %x\n", data)
+	}
+
+	accessList := s.accessList(*accessListSpec, toAddress, value, data)
+	estimate := s.estimateGas(toAddress, value, data, accessList)
+	gasLimit := estimate.Limit
+
+	// 准备交易费用，交易对象在分配nonce后创建
+	fees := s.suggestFees(*gf.legacy)
+
+	// 模拟执行与 nonce 无关，用相同参数的交易在发送前模拟
+	if sf.enabled() {
+		s.simulate(fees.newTx(s.chainID, 0, toAddress, value, gasLimit, data, accessList))
+		if *sf.dryRun {
+			return
+		}
+	}
+
+	// 由nonce管理器分配nonce并发送，节点报告nonce过低时重新同步nonce后重试
+	var signedTx *types.Transaction
+	nonces := nonce.NewManager(client)
+	sentNonce, err := nonces.Submit(context.Background(), fromAddress, func(n uint64) error {
+		fmt.Printf("正在创建交易对象（nonce %d）...\n", n)
+		tx := fees.newTx(s.chainID, n, toAddress, value, gasLimit, data, accessList)
+
+		// 签名前检查余额是否足以支付最坏情况费用（金额 + Gas限制 * 最高Gas价格）
+		fmt.Println("正在检查账户余额...")
+		balance, err := txutil.CheckBalance(context.Background(), client, fromAddress, tx.Cost())
+		if err != nil {
+			log.Fatal("❌ 余额检查失败:", err)
+		}
+		fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(tx.Cost()))
+
+		// 对交易进行签名（legacy交易使用EIP155规则，其他类型的交易使用对应EIP的规则）
+		fmt.Println("正在对交易进行签名...")
+		signedTx, err = s.key.SignTx(tx, types.LatestSignerForChainID(s.chainID))
+		if err != nil {
+			log.Fatal("❌ 交易签名失败:", err)
+		}
+		fmt.Println("✅ 交易签名成功")
+
+		// 发送交易到网络
+		fmt.Printf("正在发送交易 %s ...\n", signedTx.Hash().Hex())
+		err = client.SendTransaction(context.Background(), signedTx)
+		if nonce.IsNonceTooLow(err) {
+			fmt.Printf("⚠️ nonce %d 已被使用，正在重新同步nonce...\n", n)
+		}
+		return err
+	})
 	if err != nil {
-		log.Fatal("❌ 获取Gas价格失败:", err)
+		log.Fatal("❌ 发送交易失败:", err)
 	}
-	fmt.Printf("✅ Gas价格获取成功: %s wei\n", gasPrice.String())
 
-	// 设置接收方地址
-	toAddress := common.HexToAddress("0x56161e6389eD71C3D4a3C60a3a0a1C17D77Ef031")
-	fmt.Printf("📧 接收方地址: %s\n", toAddress.Hex())
+	// 打印交易哈希
+	fmt.Printf("🎉 交易已成功发送!\n")
+	fmt.Printf("🔗 交易哈希: %s\n", signedTx.Hash().Hex())
+	fmt.Printf("📋 交易详情:\n")
+	fmt.Printf("   发送方: %s\n", fromAddress.Hex())
+	fmt.Printf("   接收方: %s\n", toAddress.Hex())
+	fmt.Printf("   金额: %s ETH\n", units.FormatEther(value))
+	fmt.Printf("   类型: %d\n", signedTx.Type())
+	fmt.Printf("   Gas限制: %d (估算 %d)\n", gasLimit, estimate.Estimate)
+	if len(accessList) > 0 {
+		fmt.Printf("   访问列表: %d 个地址, %d 个存储槽\n", len(accessList), accessList.StorageKeys())
+	}
+	if fees.legacy {
+		fmt.Printf("   Gas价格: %s gwei\n", units.FormatGwei(signedTx.GasPrice()))
+	} else {
+		fmt.Printf("   小费上限: %s gwei\n", units.FormatGwei(signedTx.GasTipCap()))
+		fmt.Printf("   总费用上限: %s gwei\n", units.FormatGwei(signedTx.GasFeeCap()))
+	}
+	fmt.Printf("   Nonce: %d\n", sentNonce)
+	if link := network.TxURL(signedTx.Hash()); link != "" {
+		fmt.Printf("🌐 区块浏览器: %s\n", link)
+	}
 
-	// 设置交易数据（这里为空）
-	var data []byte
-	fmt.Printf("📄 交易数据: %x (空数据)\n", data)
+	if *cf.confirmations == 0 {
+		return
+	}
+	s.waitConfirmed(*cf.confirmations, *cf.timeout, nil, signedTx)
+}
 
-	// 创建交易对象
-	fmt.Println("正在创建交易对象...")
-	tx := types.NewTransaction(nonce, toAddress, value, gasLimit, gasPrice, data)
-	fmt.Println("✅ 交易对象创建成功")
+// runBatch 按转账文件批量发送转账：先校验全部记录，再以连续的 nonce 限速发送，
+// 每一步都写入进度文件，中断后以相同参数重新运行即可从中断处继续
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	cf := addCommonFlags(fs)
+	gf := addGasFlags(fs)
+	file := fs.String("file", "", "批量转账文件（.csv 或 .json）")
+	unit := fs.String("unit", "ether", "金额未带单位时使用的单位: wei、gwei 或 ether")
+	progressPath := fs.String("progress", "", "进度文件路径（默认为 <转账文件>.progress.json）")
+	reportPath := fs.String("report", "", "结果报告输出路径（.csv 或 .json），为空时只打印汇总")
+	rate := fs.Float64("rate", 1, "每秒最多发送的交易数")
+	fs.Parse(args)
+	if *file == "" {
+		log.Fatal("❌ 请使用 -file 指定批量转账文件")
+	}
+	if *rate <= 0 {
+		log.Fatal("❌ -rate 必须大于 0")
+	}
+	var reportFormat string
+	if *reportPath != "" {
+		var err error
+		if reportFormat, err = batch.ReportFormat(*reportPath); err != nil {
+			log.Fatal("❌ ", err)
+		}
+	}
+	if *progressPath == "" {
+		*progressPath = *file + ".progress.json"
+	}
 
-	// 获取网络链ID
-	fmt.Println("正在获取网络链ID...")
-	chainID, err := client.NetworkID(context.Background())
+	// 发送任何交易之前校验整个文件
+	fmt.Printf("正在读取并校验转账文件 %s...\n", *file)
+	transfers, err := batch.LoadFile(*file, *unit)
 	if err != nil {
-		log.Fatal("❌ 获取链ID失败:", err)
+		log.Fatal("❌ 转账文件校验失败: ", err)
+	}
+	total := new(big.Int)
+	for _, t := range transfers {
+		total.Add(total, t.Amount)
+	}
+	fmt.Printf("✅ 校验通过: %d 笔转账, 总金额 %s ETH\n", len(transfers), units.FormatEther(total))
+
+	s := cf.open()
+	client, network, from := s.client, s.network, s.key.Address
+	gf.apply(network)
+	ctx := context.Background()
+
+	progress, err := batch.OpenProgress(*progressPath, transfers)
+	if err != nil {
+		log.Fatal("❌ 打开进度文件失败:", err)
+	}
+	save := func() {
+		if err := progress.Save(); err != nil {
+			log.Fatal("❌ ", err)
+		}
+	}
+	fmt.Printf("📒 进度文件: %s\n", *progressPath)
+
+	// 已签名但发送结果未知的转账：节点能查到交易说明已发送，否则重新发送
+	for _, r := range progress.Results {
+		if r.Status != batch.StatusSigned {
+			continue
+		}
+		if _, _, err := client.TransactionByHash(ctx, *r.Hash); err == nil {
+			r.Status, r.Error = batch.StatusSent, ""
+		} else if errors.Is(err, ethereum.NotFound) {
+			r.Status, r.Nonce, r.Hash = batch.StatusPending, nil, nil
+		} else {
+			log.Fatal("❌ 查询交易失败:", err)
+		}
+	}
+	save()
+
+	var todo []*batch.Result
+	for _, r := range progress.Results {
+		if r.Status == batch.StatusPending || r.Status == batch.StatusError {
+			todo = append(todo, r)
+		}
+	}
+	counts := progress.Counts()
+	fmt.Printf("📋 待发送 %d 笔, 已发送待确认 %d 笔, 已完成 %d 笔, 已被替换需核对 %d 笔\n",
+		len(todo), counts[batch.StatusSent], counts[batch.StatusConfirmed]+counts[batch.StatusFailed], counts[batch.StatusReplaced])
+
+	if len(todo) > 0 {
+		// 估算每笔转账的Gas并确认余额足以支付全部待发送转账的最坏情况费用
+		fees := s.suggestFees(false)
+		fmt.Println("正在估算Gas用量...")
+		gasLimits := make(map[int]uint64, len(todo))
+		cost := new(big.Int)
+		for _, r := range todo {
+			estimate, err := txutil.EstimateGasLimit(ctx, client, ethereum.CallMsg{
+				From:  from,
+				To:    &r.To,
+				Value: r.Amount,
+			}, network.Gas.LimitMultiplier, network.Gas.LimitCap)
+			if err != nil {
+				log.Fatalf("❌ 估算第 %d 行转账的Gas失败: %v", r.Line, err)
+			}
+			gasLimits[r.Index] = estimate.Limit
+			cost.Add(cost, fees.newTx(s.chainID, 0, r.To, r.Amount, estimate.Limit, nil, nil).Cost())
+		}
+		fmt.Println("正在检查账户余额...")
+		balance, err := txutil.CheckBalance(ctx, client, from, cost)
+		if err != nil {
+			log.Fatal("❌ 余额检查失败:", err)
+		}
+		fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况总费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(cost))
+
+		// 以连续的nonce限速发送
+		nonces := nonce.NewManager(client)
+		limiter := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer limiter.Stop()
+		for i, r := range todo {
+			if i > 0 {
+				<-limiter.C
+			}
+			n, err := nonces.Submit(ctx, from, func(n uint64) error {
+				tx, err := s.key.SignTx(fees.newTx(s.chainID, n, r.To, r.Amount, gasLimits[r.Index], nil, nil), types.LatestSignerForChainID(s.chainID))
+				if err != nil {
+					return err
+				}
+				// 发送前记录nonce与哈希，中断后可以查询这笔交易是否已发送
+				hash := tx.Hash()
+				r.Status, r.Nonce, r.Hash, r.Error = batch.StatusSigned, &n, &hash, ""
+				save()
+				return client.SendTransaction(ctx, tx)
+			})
+			if err != nil {
+				// 节点可能已经接受了交易（如提交后连接中断），已签名的转账保留nonce与哈希，
+				// 下次运行时先向节点查询，确认未发送后才重新发送
+				if r.Status != batch.StatusSigned {
+					r.Status = batch.StatusError
+				}
+				r.Error = err.Error()
+				save()
+				fmt.Printf("❌ [%d/%d] 第 %d 行 -> %s 发送失败: %v\n", i+1, len(todo), r.Line, r.To.Hex(), err)
+				continue
+			}
+			r.Status = batch.StatusSent
+			save()
+			fmt.Printf("📤 [%d/%d] 第 %d 行 -> %s %s ETH, nonce %d, 交易 %s\n",
+				i+1, len(todo), r.Line, r.To.Hex(), units.FormatEther(r.Amount), n, r.Hash.Hex())
+		}
+		if gaps := nonces.Gaps(from); len(gaps) > 0 {
+			fmt.Printf("⚠️ nonce %v 已归还但未再使用，更高 nonce 的转账会卡在交易池中，重新运行或发送任意交易填补后才能上链\n", gaps)
+		}
+	}
+
+	// 等待已发送的转账确认
+	if *cf.confirmations > 0 {
+		fmt.Printf("⏳ 等待已发送的转账达到 %d 个确认...\n", *cf.confirmations)
+		for _, r := range progress.Results {
+			if r.Status != batch.StatusSent {
+				continue
+			}
+			waitBatchResult(s, r, *cf.confirmations, *cf.timeout)
+			save()
+		}
+	}
+
+	// 汇总与报告
+	counts = progress.Counts()
+	fmt.Println("========== 批量转账结果 ==========")
+	fmt.Printf("   成功: %d\n", counts[batch.StatusConfirmed])
+	fmt.Printf("   失败: %d\n", counts[batch.StatusFailed])
+	fmt.Printf("   已被替换需核对: %d\n", counts[batch.StatusReplaced])
+	fmt.Printf("   已发送待确认: %d\n", counts[batch.StatusSent])
+	fmt.Printf("   发送结果未知: %d\n", counts[batch.StatusSigned])
+	fmt.Printf("   出错待重试: %d\n", counts[batch.StatusError])
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			log.Fatal("❌ 创建报告文件失败:", err)
+		}
+		if err := batch.WriteReport(f, reportFormat, progress.Results); err != nil {
+			f.Close()
+			log.Fatal("❌ 写入报告失败:", err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal("❌ 写入报告失败:", err)
+		}
+		fmt.Printf("📄 结果报告: %s\n", *reportPath)
+	}
+	if counts[batch.StatusError] > 0 || counts[batch.StatusSent] > 0 || counts[batch.StatusSigned] > 0 {
+		fmt.Println("⚠️ 仍有未完成的转账，以相同参数重新运行即可继续")
+	}
+	if counts[batch.StatusReplaced] > 0 {
+		fmt.Println("⚠️ 有转账的 nonce 被其他交易使用，重新运行不会再发送这些转账，请人工核对是否已到账")
+		if link := s.network.AddressURL(s.from); link != "" {
+			fmt.Printf("🌐 账户交易记录: %s\n", link)
+		}
+	}
+}
+
+// waitBatchResult 等待批量转账中的一笔交易确认并更新其结果。只有交易确定被丢弃时才标记为可重试；
+// nonce 被另一笔交易使用时多半是对这笔转账执行了加速，重新发送会重复转账，因此标记为需要人工核对；
+// 超时或查询节点出错时交易可能仍在交易池中，保持已发送状态
+func waitBatchResult(s *session, r *batch.Result, confirmations uint64, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tx, _, err := s.client.TransactionByHash(ctx, *r.Hash)
+	if err == nil {
+		var result *txutil.Confirmation
+		result, err = txutil.WaitConfirmed(ctx, s.client, s.from, txutil.WaitOptions{Confirmations: confirmations}, tx)
+		if err == nil {
+			r.Block = result.Receipt.BlockNumber.Uint64()
+			r.GasUsed = result.Receipt.GasUsed
+			r.Fee = result.Fee()
+			r.Status = batch.StatusConfirmed
+			if result.Receipt.Status != types.ReceiptStatusSuccessful {
+				r.Status = batch.StatusFailed
+				r.Error = "交易执行失败"
+			}
+			fmt.Printf("   第 %d 行 -> %s: %s (区块 %d)\n", r.Line, r.To.Hex(), r.Status, r.Block)
+			return
+		}
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		err = txutil.ErrDropped
+	}
+	switch {
+	case errors.Is(err, txutil.ErrDropped):
+		r.Status, r.Error = batch.StatusError, err.Error()
+		fmt.Printf("   第 %d 行 -> %s: %v\n", r.Line, r.To.Hex(), err)
+	case errors.Is(err, txutil.ErrReplaced):
+		r.Status = batch.StatusReplaced
+		r.Error = fmt.Sprintf("nonce %d 已被另一笔交易使用（可能是加速或取消），不会自动重发，请核对账户的交易记录", *r.Nonce)
+		fmt.Printf("   第 %d 行 -> %s: ⚠️ %s\n", r.Line, r.To.Hex(), r.Error)
+	case errors.Is(err, context.DeadlineExceeded):
+		r.Error = "等待确认超时"
+		fmt.Printf("   第 %d 行 -> %s: 等待确认超时\n", r.Line, r.To.Hex())
+	default:
+		r.Error = err.Error()
+		fmt.Printf("   第 %d 行 -> %s: 查询失败，下次运行继续等待: %v\n", r.Line, r.To.Hex(), err)
+	}
+}
+
+// gasFlags 是发送新交易的子命令共用的 Gas 参数
+type gasFlags struct {
+	legacy        *bool
+	gasMultiplier *float64
+	gasCap        *uint64
+}
+
+func addGasFlags(fs *flag.FlagSet) *gasFlags {
+	return &gasFlags{
+		legacy:        fs.Bool("legacy", false, "发送 legacy 交易（gasPrice），用于尚未启用 EIP-1559 的链"),
+		gasMultiplier: fs.Float64("gas-multiplier", 0, "Gas 估算值的安全系数，覆盖网络配置（默认 1.2）"),
+		gasCap:        fs.Uint64("gas-cap", 0, "Gas 限制的上限，覆盖网络配置（0 表示使用配置）"),
+	}
+}
+
+// apply 用命令行参数覆盖网络配置中的 Gas 设置
+func (f *gasFlags) apply(network *config.Network) {
+	if *f.legacy {
+		network.Gas.Legacy = true
+	}
+	if *f.gasMultiplier > 0 {
+		network.Gas.LimitMultiplier = *f.gasMultiplier
+	}
+	if *f.gasCap > 0 {
+		network.Gas.LimitCap = *f.gasCap
+	}
+}
+
+// simFlags 是发送前模拟执行交易的命令行参数
+type simFlags struct {
+	simulate *bool
+	dryRun   *bool
+}
+
+func addSimFlags(fs *flag.FlagSet) *simFlags {
+	return &simFlags{
+		simulate: fs.Bool("simulate", false, "发送前在待处理区块上模拟执行交易，执行失败时不发送"),
+		dryRun:   fs.Bool("dry-run", false, "只模拟执行交易，不签名也不发送"),
+	}
+}
+
+// enabled 报告是否需要模拟执行，-dry-run 隐含 -simulate
+func (f *simFlags) enabled() bool {
+	return *f.simulate || *f.dryRun
+}
+
+// simulate 以 eth_call 在待处理区块上模拟执行交易并打印结果，执行失败时退出
+func (s *session) simulate(tx *types.Transaction) {
+	fmt.Println("🧪 正在模拟执行交易...")
+	result, err := txutil.Simulate(context.Background(), s.client, txutil.TxCallMsg(s.from, tx), nil)
+	if err != nil {
+		log.Fatal("❌ 模拟执行失败:", err)
+	}
+	if result.Err != nil {
+		log.Fatal("❌ 模拟执行失败，交易不会发送: ", result.Err)
+	}
+	fmt.Printf("✅ 模拟执行成功: Gas用量 %d (Gas限制 %d)\n", result.GasUsed, tx.Gas())
+}
+
+// failureReason 在父区块状态上重放执行失败的交易，返回失败原因的说明
+func (s *session) failureReason(tx *types.Transaction, receipt *types.Receipt) string {
+	fmt.Println("🔍 正在重放失败的交易以获取失败原因...")
+	reason, err := txutil.ReplayFailure(context.Background(), s.client, s.from, tx, receipt, nil)
+	if err != nil {
+		return fmt.Sprintf("交易被回滚（重放交易失败: %v）", err)
+	}
+	return reason.Error()
+}
+
+// txFees 是创建新交易使用的费用，legacy 交易只使用 gasPrice
+type txFees struct {
+	legacy   bool
+	gasPrice *big.Int
+	dynamic  *txutil.DynamicFees
+}
+
+// suggestFees 获取节点建议的费用并应用网络配置中的限制，legacy 为 true 时获取 Gas 价格
+func (s *session) suggestFees(legacy bool) *txFees {
+	client, network := s.client, s.network
+	if legacy || network.Gas.Legacy {
+		// 获取建议的Gas价格
+		fmt.Println("正在获取建议的Gas价格...")
+		gasPrice, err := client.SuggestGasPrice(context.Background())
+		if err != nil {
+			log.Fatal("❌ 获取Gas价格失败:", err)
+		}
+		fmt.Printf("✅ Gas价格获取成功: %s gwei\n", units.FormatGwei(gasPrice))
+		if maxPrice := network.Gas.GasFeeCap; maxPrice != nil && gasPrice.Cmp(maxPrice) > 0 {
+			gasPrice = maxPrice
+			fmt.Printf("⚠️ Gas价格超过配置上限，使用上限: %s gwei\n", units.FormatGwei(gasPrice))
+		}
+		return &txFees{legacy: true, gasPrice: gasPrice}
+	}
+
+	// 获取建议的小费与最新区块的基础费用
+	fmt.Println("正在获取建议的EIP-1559费用...")
+	fees, err := txutil.SuggestDynamicFees(context.Background(), client)
+	if err != nil {
+		log.Fatal("❌ 获取EIP-1559费用失败:", err)
+	}
+	if err := fees.ApplyLimits(network.Gas.GasTipCap, network.Gas.GasFeeCap); err != nil {
+		log.Fatal("❌ 配置的 maxFeePerGas 过低: ", err)
+	}
+	fmt.Printf("✅ 费用获取成功: 基础费用 %s gwei, 小费上限 %s gwei, 总费用上限 %s gwei\n",
+		units.FormatGwei(fees.BaseFee), units.FormatGwei(fees.GasTipCap), units.FormatGwei(fees.GasFeeCap))
+	return &txFees{dynamic: fees}
+}
+
+// newTx 创建未签名的转账交易。带访问列表的 legacy 交易创建为 EIP-2930 访问列表交易（类型 1），
+// EIP-1559 交易直接携带访问列表
+func (f *txFees) newTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gas uint64, data []byte, accessList types.AccessList) *types.Transaction {
+	if f.legacy && len(accessList) == 0 {
+		return types.NewTransaction(nonce, to, value, gas, f.gasPrice, data)
+	}
+	if f.legacy {
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   f.gasPrice,
+			Gas:        gas,
+			To:         &to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:    chainID,
+		Nonce:      nonce,
+		GasTipCap:  f.dynamic.GasTipCap,
+		GasFeeCap:  f.dynamic.GasFeeCap,
+		Gas:        gas,
+		To:         &to,
+		Value:      value,
+		Data:       data,
+		AccessList: accessList,
+	})
+}
+
+// parseTransfer 解析接收方地址与转账金额（未带单位时按 ether），无效时退出
+func parseTransfer(to, amount string) (common.Address, *big.Int) {
+	value, err := units.ParseAmount(amount, "ether")
+	if err != nil {
+		log.Fatal("❌ 解析转账金额失败:", err)
+	}
+	if !common.IsHexAddress(to) {
+		log.Fatalf("❌ 无效的接收方地址 %q", to)
+	}
+	return common.HexToAddress(to), value
+}
+
+// parseData 解析十六进制的交易数据，无效时退出
+func parseData(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	data, err := hexutil.Decode(s)
+	if err != nil {
+		log.Fatalf("❌ 无效的交易数据 %q: %v", s, err)
+	}
+	return data
+}
+
+// addAccessListFlag 添加 -access-list 参数
+func addAccessListFlag(fs *flag.FlagSet) *string {
+	return fs.String("access-list", "", "EIP-2930 访问列表: auto 通过 eth_createAccessList 生成并在能节省 Gas 时使用，"+
+		"或访问列表 JSON 文件路径；为空时不使用")
+}
+
+// accessList 按 -access-list 参数准备交易的访问列表并打印与不带访问列表时的 Gas 对比。
+// auto 生成的访问列表不能节省 Gas 时不使用；返回 nil 表示不使用访问列表
+func (s *session) accessList(spec string, to common.Address, value *big.Int, data []byte) types.AccessList {
+	if spec == "" {
+		return nil
+	}
+	ctx := context.Background()
+	msg := ethereum.CallMsg{From: s.from, To: &to, Value: value, Data: data}
+	var (
+		result *txutil.AccessListResult
+		err    error
+	)
+	if spec == "auto" {
+		fmt.Println("正在通过 eth_createAccessList 生成访问列表...")
+		result, err = txutil.CreateAccessList(ctx, s.client.Client(), msg)
+	} else {
+		fmt.Printf("正在读取访问列表 %s...\n", spec)
+		var list types.AccessList
+		if list, err = txutil.LoadAccessList(spec); err == nil {
+			result, err = txutil.CompareAccessList(ctx, s.client, msg, list)
+		}
+	}
+	if err != nil {
+		log.Fatal("❌ 准备访问列表失败:", err)
+	}
+	fmt.Printf("📋 访问列表: %s\n", result)
+	if spec == "auto" && result.Saved() <= 0 {
+		fmt.Println("⚠️ 访问列表不能节省Gas，不使用访问列表")
+		return nil
+	}
+	return result.AccessList
+}
+
+// estimateGas 估算从发送方发出的交易的 Gas 限制，失败时退出
+func (s *session) estimateGas(to common.Address, value *big.Int, data []byte, accessList types.AccessList) *txutil.GasLimit {
+	// 估算Gas用量（接收方是合约时转账会执行其代码，不能假定为21000）
+	fmt.Println("正在估算Gas用量...")
+	estimate, err := txutil.EstimateGasLimit(context.Background(), s.client, ethereum.CallMsg{
+		From:       s.from,
+		To:         &to,
+		Value:      value,
+		Data:       data,
+		AccessList: accessList,
+	}, s.network.Gas.LimitMultiplier, s.network.Gas.LimitCap)
+	if err != nil {
+		if execErr := txutil.ExecutionError(err, nil); execErr != nil {
+			log.Fatal("❌ 交易会执行失败，交易不会发送: ", execErr)
+		}
+		log.Fatal("❌ 估算Gas失败:", err)
+	}
+	fmt.Printf("⛽ Gas: %s\n", estimate)
+	return estimate
+}
+
+// runReplace 以相同的 nonce 替换一笔待处理交易：speedup 提高费用重新发送原交易，
+// cancel 发送 0 金额的自转账使原交易失效，之后跟踪竞争的交易中哪一笔最终上链
+func runReplace(name string, args []string, cancel bool) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	cf := addCommonFlags(fs)
+	nonceFlag := fs.Int64("nonce", -1, "要替换的交易的 nonce（通过节点交易池查找，节点需支持 txpool_contentFrom）")
+	txHash := fs.String("tx", "", "要替换的交易哈希，节点不支持交易池查询时使用")
+	bump := fs.Uint64("bump", txutil.MinReplacementBump, "费用提高的百分比，低于 10 时按 10 计算")
+	fs.Parse(args)
+	if *nonceFlag < 0 && *txHash == "" {
+		log.Fatal("❌ 请使用 -nonce 或 -tx 指定要替换的交易")
+	}
+
+	s := cf.open()
+	client, network, from := s.client, s.network, s.key.Address
+	ctx := context.Background()
+
+	// 查找待处理的原交易
+	fmt.Println("正在查找待处理的原交易...")
+	var (
+		old *types.Transaction
+		err error
+	)
+	if *txHash != "" {
+		var pending bool
+		old, pending, err = client.TransactionByHash(ctx, common.HexToHash(*txHash))
+		if err != nil {
+			log.Fatal("❌ 查询交易失败:", err)
+		}
+		if !pending {
+			log.Fatal("❌ 交易已上链，无需替换")
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(old.ChainId()), old)
+		if err != nil {
+			log.Fatal("❌ 恢复交易发送方失败:", err)
+		}
+		if sender != from {
+			log.Fatalf("❌ 交易发送方 %s 与当前账户 %s 不一致", sender.Hex(), from.Hex())
+		}
+		if *nonceFlag >= 0 && old.Nonce() != uint64(*nonceFlag) {
+			log.Fatalf("❌ 交易的 nonce %d 与 -nonce %d 不一致", old.Nonce(), *nonceFlag)
+		}
+	} else {
+		old, err = txutil.FindPendingTx(ctx, client.Client(), from, uint64(*nonceFlag))
+		if err != nil {
+			log.Fatal("❌ 查找待处理交易失败:", err)
+		}
+	}
+	fmt.Printf("✅ 原交易: %s (nonce %d, 类型 %d)\n", old.Hash().Hex(), old.Nonce(), old.Type())
+	if !txutil.CanReplace(old.Type()) {
+		log.Fatalf("❌ 不支持替换类型为 %d 的交易", old.Type())
+	}
+
+	// 获取当前建议费用，替换交易的每项费用取原费用提高后与建议费用中的较大者
+	var fees txutil.ReplacementFees
+	if old.Type() == types.DynamicFeeTxType || old.Type() == types.SetCodeTxType {
+		fmt.Println("正在获取建议的EIP-1559费用...")
+		if fees.Dynamic, err = txutil.SuggestDynamicFees(ctx, client); err != nil {
+			log.Fatal("❌ 获取EIP-1559费用失败:", err)
+		}
+		if err := fees.Dynamic.ApplyLimits(network.Gas.GasTipCap, nil); err != nil {
+			log.Fatal("❌ ", err)
+		}
+	} else {
+		fmt.Println("正在获取建议的Gas价格...")
+		if fees.GasPrice, err = client.SuggestGasPrice(ctx); err != nil {
+			log.Fatal("❌ 获取Gas价格失败:", err)
+		}
+	}
+
+	var tx *types.Transaction
+	if cancel {
+		tx, err = txutil.CancelTx(old, from, s.chainID, fees, *bump)
+	} else {
+		tx, err = txutil.SpeedUpTx(old, s.chainID, fees, *bump)
+	}
+	if err != nil {
+		log.Fatal("❌ 创建替换交易失败:", err)
+	}
+	// 替换交易的费用不能低于原交易提高后的值，超过配置上限时只能放弃而不能截断
+	if maxFee := network.Gas.GasFeeCap; maxFee != nil && tx.GasFeeCap().Cmp(maxFee) > 0 {
+		log.Fatalf("❌ 替换交易需要的费用上限 %s gwei 超过配置的上限 %s gwei", units.FormatGwei(tx.GasFeeCap()), units.FormatGwei(maxFee))
+	}
+	fmt.Println("🔧 替换交易费用:")
+	if tx.Type() == types.DynamicFeeTxType || tx.Type() == types.SetCodeTxType {
+		fmt.Printf("   小费上限: %s -> %s gwei\n", units.FormatGwei(old.GasTipCap()), units.FormatGwei(tx.GasTipCap()))
+		fmt.Printf("   总费用上限: %s -> %s gwei\n", units.FormatGwei(old.GasFeeCap()), units.FormatGwei(tx.GasFeeCap()))
+	} else {
+		fmt.Printf("   Gas价格: %s -> %s gwei\n", units.FormatGwei(old.GasPrice()), units.FormatGwei(tx.GasPrice()))
+	}
+
+	// 签名并发送替换交易
+	fmt.Println("正在对替换交易进行签名...")
+	signedTx, err := s.key.SignTx(tx, types.LatestSignerForChainID(s.chainID))
+	if err != nil {
+		log.Fatal("❌ 交易签名失败:", err)
+	}
+	fmt.Printf("正在发送替换交易 %s ...\n", signedTx.Hash().Hex())
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		log.Fatal("❌ 发送替换交易失败:", err)
+	}
+	fmt.Printf("🎉 替换交易已发送: %s\n", signedTx.Hash().Hex())
+	if link := network.TxURL(signedTx.Hash()); link != "" {
+		fmt.Printf("🌐 区块浏览器: %s\n", link)
+	}
+
+	if *cf.confirmations == 0 {
+		return
+	}
+	label := "加速交易"
+	if cancel {
+		label = "取消交易"
+	}
+	s.waitConfirmed(*cf.confirmations, *cf.timeout, map[common.Hash]string{
+		old.Hash():      "原交易",
+		signedTx.Hash(): label,
+	}, old, signedTx)
+}
+
+// runBuild 在联网机器上创建未签名的转账交易：从节点获取 nonce、费用与链ID，
+// 估算 Gas 后写入 JSON 信封，交给离线机器上的 sign 子命令签名
+func runBuild(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	nf := addNetworkFlags(fs)
+	gf := addGasFlags(fs)
+	simulate := fs.Bool("simulate", false, "写入信封前在待处理区块上模拟执行交易，执行失败时退出")
+	from := fs.String("from", "", "发送方地址，即离线签名使用的账户")
+	to := fs.String("to", "0x56161e6389eD71C3D4a3C60a3a0a1C17D77Ef031", "接收方地址")
+	amount := fs.String("amount", "0.001", "转账金额，可带单位后缀，如 0.001、0.5 ether、20 gwei、1000 wei（默认单位 ether）")
+	dataHex := fs.String("data", "", "交易数据（十六进制），接收方为合约时使用")
+	accessListSpec := addAccessListFlag(fs)
+	nonceFlag := fs.Int64("nonce", -1, "交易的 nonce，-1 表示使用节点返回的待处理 nonce")
+	out := fs.String("out", "unsigned-tx.json", "未签名交易信封的输出路径")
+	fs.Parse(args)
+	if !common.IsHexAddress(*from) {
+		log.Fatalf("❌ 请使用 -from 指定有效的发送方地址，当前为 %q", *from)
+	}
+	toAddress, value := parseTransfer(*to, *amount)
+	data := parseData(*dataHex)
+
+	_, s := nf.connect()
+	s.from = common.HexToAddress(*from)
+	client := s.client
+	gf.apply(s.network)
+	ctx := context.Background()
+	fmt.Printf("📬 发送方地址: %s\n", s.from.Hex())
+
+	accessList := s.accessList(*accessListSpec, toAddress, value, data)
+	estimate := s.estimateGas(toAddress, value, data, accessList)
+	fees := s.suggestFees(*gf.legacy)
+
+	// 获取nonce，离线签名期间账户不能再发送其他交易，否则需要用 -nonce 指定
+	n := uint64(*nonceFlag)
+	if *nonceFlag < 0 {
+		fmt.Println("正在获取nonce...")
+		pending, err := client.PendingNonceAt(ctx, s.from)
+		if err != nil {
+			log.Fatal("❌ 获取nonce失败:", err)
+		}
+		n = pending
+	}
+	tx := fees.newTx(s.chainID, n, toAddress, value, estimate.Limit, data, accessList)
+	if *simulate {
+		s.simulate(tx)
+	}
+
+	// 广播时才会真正扣款，这里先确认当前余额足以支付最坏情况费用
+	fmt.Println("正在检查账户余额...")
+	balance, err := txutil.CheckBalance(ctx, client, s.from, tx.Cost())
+	if err != nil {
+		log.Fatal("❌ 余额检查失败:", err)
+	}
+	fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(tx.Cost()))
+
+	env, err := txutil.NewEnvelope(s.chainID, s.from, tx)
+	if err != nil {
+		log.Fatal("❌ 创建交易信封失败:", err)
+	}
+	if err := env.Save(*out); err != nil {
+		log.Fatal("❌ ", err)
+	}
+	printTx(s.chainID, s.from, tx)
+	fmt.Printf("📄 未签名交易已写入: %s\n", *out)
+	fmt.Println("👉 在离线机器上执行 sign 子命令签名，再用 broadcast 子命令发送")
+}
+
+// runSign 在离线机器上对未签名交易信封签名，输出十六进制的已签名交易，全程不访问节点
+func runSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	nf := addNetworkFlags(fs)
+	kf := addKeyFlags(fs)
+	in := fs.String("in", "unsigned-tx.json", "未签名交易信封的路径")
+	out := fs.String("out", "signed-tx.hex", "已签名交易（十六进制）的输出路径")
+	fs.Parse(args)
+
+	fmt.Printf("正在读取交易信封 %s...\n", *in)
+	env, err := txutil.LoadEnvelope(*in)
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+	tx, err := env.Transaction()
+	if err != nil {
+		log.Fatal("❌ ", err)
+	}
+
+	// 离线核对信封的链ID与网络配置中的预期链ID，避免把交易签到错误的链上
+	cfg, network := nf.loadConfig()
+	if expected := network.ExpectedChainID(); expected == nil {
+		nf.chainMismatch(txutil.ErrNoExpectedChainID)
+	} else if expected.Cmp(env.ChainID) != 0 {
+		nf.chainMismatch(fmt.Errorf("交易信封的链ID %s 与网络 %s 配置的预期链ID %s 不一致", env.ChainID, network.Name, expected))
+	}
+	printTx(env.ChainID, env.From, tx)
+
+	key := kf.load(cfg)
+	if key.Address != env.From {
+		log.Fatalf("❌ 私钥地址 %s 与交易信封的发送方 %s 不一致", key.Address.Hex(), env.From.Hex())
 	}
-	fmt.Printf("✅ 链ID获取成功: %s\n", chainID.String())
 
-	// 使用EIP155签名规则对交易进行签名
 	fmt.Println("正在对交易进行签名...")
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := key.SignTx(tx, env.Signer())
 	if err != nil {
 		log.Fatal("❌ 交易签名失败:", err)
 	}
+	raw, err := txutil.EncodeRawTx(signedTx)
+	if err != nil {
+		log.Fatal("❌ 编码交易失败:", err)
+	}
+	if err := os.WriteFile(*out, []byte(raw+"\n"), 0o644); err != nil {
+		log.Fatal("❌ 写入已签名交易失败:", err)
+	}
 	fmt.Println("✅ 交易签名成功")
+	fmt.Printf("🔗 交易哈希: %s\n", signedTx.Hash().Hex())
+	fmt.Printf("📄 已签名交易已写入: %s\n", *out)
+}
 
-	// 发送交易到网络
-	fmt.Printf("正在发送交易 %s ...\n", signedTx.Hash().Hex())
-	err = client.SendTransaction(context.Background(), signedTx)
+// runBroadcast 发送十六进制的已签名交易，发送前核对链ID、nonce 与余额
+func runBroadcast(args []string) {
+	fs := flag.NewFlagSet("broadcast", flag.ExitOnError)
+	nf := addNetworkFlags(fs)
+	wf := addWaitFlags(fs)
+	sf := addSimFlags(fs)
+	in := fs.String("in", "signed-tx.hex", "已签名交易（十六进制）文件的路径")
+	rawFlag := fs.String("raw", "", "已签名交易的十六进制字符串，优先于 -in")
+	fs.Parse(args)
+
+	raw := *rawFlag
+	if raw == "" {
+		data, err := os.ReadFile(*in)
+		if err != nil {
+			log.Fatal("❌ 读取已签名交易失败:", err)
+		}
+		raw = string(data)
+	}
+	tx, err := txutil.DecodeRawTx(raw)
 	if err != nil {
-		log.Fatal("❌ 发送交易失败:", err)
+		log.Fatal("❌ ", err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		log.Fatal("❌ 恢复交易发送方失败:", err)
 	}
 
-	// 打印交易哈希
+	_, s := nf.connect()
+	s.from = sender
+	client, network := s.client, s.network
+	ctx := context.Background()
+	if tx.ChainId().Cmp(s.chainID) != 0 {
+		log.Fatalf("❌ 交易的链ID %s 与节点链ID %s 不一致", tx.ChainId(), s.chainID)
+	}
+	printTx(s.chainID, sender, tx)
+
+	// 交易的 nonce 已被使用时节点会拒绝，提前给出明确的提示
+	fmt.Println("正在检查nonce与账户余额...")
+	latest, err := client.NonceAt(ctx, sender, nil)
+	if err != nil {
+		log.Fatal("❌ 获取nonce失败:", err)
+	}
+	if tx.Nonce() < latest {
+		log.Fatalf("❌ nonce %d 已被使用（账户当前 nonce 为 %d），请重新创建并签名交易", tx.Nonce(), latest)
+	}
+	balance, err := txutil.CheckBalance(ctx, client, sender, tx.Cost())
+	if err != nil {
+		log.Fatal("❌ 余额检查失败:", err)
+	}
+	fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(tx.Cost()))
+	if sf.enabled() {
+		s.simulate(tx)
+		if *sf.dryRun {
+			return
+		}
+	}
+
+	fmt.Printf("正在发送交易 %s ...\n", tx.Hash().Hex())
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		if !nonce.IsAlreadyKnown(err) {
+			log.Fatal("❌ 发送交易失败:", err)
+		}
+		fmt.Println("⚠️ 节点交易池中已有该交易")
+	}
+	fmt.Printf("🎉 交易已成功发送!\n")
+	fmt.Printf("🔗 交易哈希: %s\n", tx.Hash().Hex())
+	if link := network.TxURL(tx.Hash()); link != "" {
+		fmt.Printf("🌐 区块浏览器: %s\n", link)
+	}
+
+	if *wf.confirmations == 0 {
+		return
+	}
+	s.waitConfirmed(*wf.confirmations, *wf.timeout, nil, tx)
+}
+
+// runDelegate 发送 EIP-7702 交易（类型 4），把授权账户的代码委托给 -delegate 指定的合约。
+// 默认由发送方自己授权；-authority-key 指定其他账户时由发送方代付 Gas
+func runDelegate(args []string) {
+	fs := flag.NewFlagSet("delegate", flag.ExitOnError)
+	cf := addCommonFlags(fs)
+	gf := addGasFlags(fs)
+	sf := addSimFlags(fs)
+	delegateFlag := fs.String("delegate", "", "委托的目标合约地址，零地址表示清除现有的委托")
+	authorityKey := fs.String("authority-key", "", "授权账户的私钥来源: keystore:<路径>、env:<变量名> 或 file:<路径>，为空时由发送方自己授权")
+	authNonceFlag := fs.Int64("auth-nonce", -1, "授权的 nonce，-1 表示自动计算（发送方自己授权时为交易 nonce + 1）")
+	anyChain := fs.Bool("any-chain", false, "授权的链ID设为 0，授权可以在任意链上使用（危险）")
+	to := fs.String("to", "", "交易的接收方，为空时调用授权账户本身，即执行委托后的代码（委托合约需能接受空调用，否则请指定 -data 或 -to）")
+	dataHex := fs.String("data", "", "交易数据（十六进制）")
+	fs.Parse(args)
+	if !common.IsHexAddress(*delegateFlag) {
+		log.Fatalf("❌ 请使用 -delegate 指定有效的委托目标地址，当前为 %q", *delegateFlag)
+	}
+	if *to != "" && !common.IsHexAddress(*to) {
+		log.Fatalf("❌ 无效的接收方地址 %q", *to)
+	}
+	delegate := common.HexToAddress(*delegateFlag)
+	data := parseData(*dataHex)
+
+	s := cf.open()
+	client, network, from := s.client, s.network, s.from
+	gf.apply(network)
+	if network.Gas.Legacy {
+		log.Fatal("❌ EIP-7702 交易使用 EIP-1559 费用，不能发送 legacy 交易")
+	}
+	ctx := context.Background()
+
+	// 授权账户默认为发送方自己，也可以由其他账户授权、发送方代付Gas
+	authority := s.key
+	if *authorityKey != "" {
+		fmt.Println("正在加载授权账户私钥...")
+		var err error
+		if authority, err = keysource.Load(*authorityKey); err != nil {
+			log.Fatal("❌ 加载授权账户私钥失败:", err)
+		}
+	}
+	sponsored := authority.Address != from
+	fmt.Printf("🔑 授权账户: %s\n", authority.Address.Hex())
+	if sponsored {
+		fmt.Printf("   由发送方 %s 代付Gas\n", from.Hex())
+	}
+	if _, ok := printDelegation(client, authority.Address); !ok && delegate == (common.Address{}) {
+		fmt.Println("⚠️ 账户当前没有委托，无需清除")
+	}
+	if delegate == (common.Address{}) {
+		fmt.Println("🧹 将清除账户的委托")
+	} else {
+		code, err := client.CodeAt(ctx, delegate, nil)
+		if err != nil {
+			log.Fatal("❌ 获取委托目标代码失败:", err)
+		}
+		if len(code) == 0 {
+			fmt.Printf("⚠️ 委托目标 %s 上没有合约代码\n", delegate.Hex())
+		}
+		fmt.Printf("🎯 委托目标: %s\n", delegate.Hex())
+	}
+
+	authChainID := s.chainID
+	if *anyChain {
+		authChainID = new(big.Int)
+		fmt.Println("⚠️ 授权的链ID为 0，该授权可以在任意链上使用")
+	}
+
+	// 授权的 nonce 必须等于执行授权时授权账户的 nonce：代付时为授权账户当前的 nonce，
+	// 自己授权时交易先使 nonce 加一，因此为交易 nonce + 1
+	authNonce := uint64(*authNonceFlag)
+	if sponsored && *authNonceFlag < 0 {
+		pending, err := client.PendingNonceAt(ctx, authority.Address)
+		if err != nil {
+			log.Fatal("❌ 获取授权账户nonce失败:", err)
+		}
+		authNonce = pending
+	}
+	signAuth := func(txNonce uint64) []types.SetCodeAuthorization {
+		n := authNonce
+		if !sponsored && *authNonceFlag < 0 {
+			n = txNonce + 1
+		}
+		auth, err := txutil.NewAuthorization(authChainID, delegate, n)
+		if err != nil {
+			log.Fatal("❌ 创建授权失败:", err)
+		}
+		signed, err := authority.SignSetCode(auth)
+		if err != nil {
+			log.Fatal("❌ 授权签名失败:", err)
+		}
+		return []types.SetCodeAuthorization{signed}
+	}
+
+	toAddress := authority.Address
+	if *to != "" {
+		toAddress = common.HexToAddress(*to)
+	}
+
+	// 用当前的待处理 nonce 预先签名授权，用于估算Gas与模拟执行
+	pending, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		log.Fatal("❌ 获取nonce失败:", err)
+	}
+	fmt.Println("正在估算Gas用量...")
+	estimate, err := txutil.EstimateGasLimit(ctx, client, ethereum.CallMsg{
+		From:              from,
+		To:                &toAddress,
+		Data:              data,
+		AuthorizationList: signAuth(pending),
+	}, network.Gas.LimitMultiplier, network.Gas.LimitCap)
+	if err != nil {
+		log.Fatal("❌ 估算Gas失败:", err)
+	}
+	fmt.Printf("⛽ Gas: %s\n", estimate)
+	fees := s.suggestFees(false)
+	newTx := func(n uint64) *types.Transaction {
+		tx, err := txutil.NewSetCodeTx(s.chainID, n, toAddress, new(big.Int), estimate.Limit, data, nil, fees.dynamic, signAuth(n))
+		if err != nil {
+			log.Fatal("❌ 创建交易失败:", err)
+		}
+		return tx
+	}
+	if sf.enabled() {
+		s.simulate(newTx(pending))
+		if *sf.dryRun {
+			return
+		}
+	}
+
+	// 由nonce管理器分配nonce并发送，nonce变化时自己授权的授权随之重新签名
+	var signedTx *types.Transaction
+	nonces := nonce.NewManager(client)
+	_, err = nonces.Submit(ctx, from, func(n uint64) error {
+		tx := newTx(n)
+		fmt.Println("正在检查账户余额...")
+		balance, err := txutil.CheckBalance(ctx, client, from, tx.Cost())
+		if err != nil {
+			log.Fatal("❌ 余额检查失败:", err)
+		}
+		fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(tx.Cost()))
+
+		fmt.Println("正在对交易进行签名...")
+		signedTx, err = s.key.SignTx(tx, types.LatestSignerForChainID(s.chainID))
+		if err != nil {
+			log.Fatal("❌ 交易签名失败:", err)
+		}
+		fmt.Printf("正在发送交易 %s ...\n", signedTx.Hash().Hex())
+		err = client.SendTransaction(ctx, signedTx)
+		if nonce.IsNonceTooLow(err) {
+			fmt.Printf("⚠️ nonce %d 已被使用，正在重新同步nonce...\n", n)
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatal("❌ 发送交易失败:", err)
+	}
+	printTx(s.chainID, from, signedTx)
 	fmt.Printf("🎉 交易已成功发送!\n")
 	fmt.Printf("🔗 交易哈希: %s\n", signedTx.Hash().Hex())
+	if link := network.TxURL(signedTx.Hash()); link != "" {
+		fmt.Printf("🌐 区块浏览器: %s\n", link)
+	}
+
+	if *cf.confirmations == 0 {
+		return
+	}
+	s.waitConfirmed(*cf.confirmations, *cf.timeout, nil, signedTx)
+
+	// 授权的 nonce 或链ID不匹配时交易仍会成功，但授权被跳过，需要核对委托是否生效
+	current, ok := printDelegation(client, authority.Address)
+	if current != delegate || (!ok && delegate != (common.Address{})) {
+		fmt.Println("⚠️ 委托未按预期生效，授权可能因 nonce 或链ID不匹配被跳过")
+		return
+	}
+	fmt.Println("✅ 委托已生效")
+}
+
+// runDelegation 查询账户当前是否设置了 EIP-7702 委托以及委托的目标合约
+func runDelegation(args []string) {
+	fs := flag.NewFlagSet("delegation", flag.ExitOnError)
+	nf := addNetworkFlags(fs)
+	address := fs.String("address", "", "要查询的账户地址")
+	fs.Parse(args)
+	if !common.IsHexAddress(*address) {
+		log.Fatalf("❌ 请使用 -address 指定有效的账户地址，当前为 %q", *address)
+	}
+	account := common.HexToAddress(*address)
+
+	_, s := nf.connect()
+	delegate, ok := printDelegation(s.client, account)
+	if ok {
+		code, err := s.client.CodeAt(context.Background(), delegate, nil)
+		if err != nil {
+			log.Fatal("❌ 获取委托目标代码失败:", err)
+		}
+		if len(code) == 0 {
+			fmt.Println("⚠️ 委托目标上没有合约代码，调用该账户时不会执行任何代码")
+		} else {
+			fmt.Printf("📄 委托目标合约代码: %d 字节\n", len(code))
+		}
+		if link := s.network.AddressURL(delegate); link != "" {
+			fmt.Printf("🌐 区块浏览器: %s\n", link)
+		}
+	}
+}
+
+// printDelegation 查询并打印账户当前的 EIP-7702 委托，返回委托的目标地址；
+// 账户没有委托时 ok 为 false，地址是合约账户或查询失败时退出
+func printDelegation(client *ethclient.Client, account common.Address) (delegate common.Address, ok bool) {
+	fmt.Printf("正在查询账户 %s 的委托...\n", account.Hex())
+	delegate, ok, err := txutil.Delegation(context.Background(), client, account)
+	if errors.Is(err, txutil.ErrNotEOA) {
+		log.Fatalf("❌ %s 是合约账户，不是 EOA", account.Hex())
+	}
+	if err != nil {
+		log.Fatal("❌ 查询委托失败:", err)
+	}
+	if ok {
+		fmt.Printf("🔗 当前委托: %s\n", delegate.Hex())
+	} else {
+		fmt.Println("📭 当前没有委托")
+	}
+	return delegate, ok
+}
+
+// printTx 打印交易内容，供签名或发送前核对
+func printTx(chainID *big.Int, from common.Address, tx *types.Transaction) {
 	fmt.Printf("📋 交易详情:\n")
-	fmt.Printf("   发送方: %s\n", fromAddress.Hex())
-	fmt.Printf("   接收方: %s\n", toAddress.Hex())
-	fmt.Printf("   金额: %s wei\n", value.String())
-	fmt.Printf("   Gas限制: %d\n", gasLimit)
-	fmt.Printf("   Gas价格: %s wei\n", gasPrice.String())
-	fmt.Printf("   Nonce: %d\n", nonce)
+	fmt.Printf("   类型: %d\n", tx.Type())
+	fmt.Printf("   链ID: %s\n", chainID)
+	fmt.Printf("   发送方: %s\n", from.Hex())
+	if tx.To() != nil {
+		fmt.Printf("   接收方: %s\n", tx.To().Hex())
+	} else {
+		fmt.Printf("   接收方: （合约创建）\n")
+	}
+	fmt.Printf("   金额: %s ETH\n", units.FormatEther(tx.Value()))
+	fmt.Printf("   Nonce: %d\n", tx.Nonce())
+	fmt.Printf("   Gas限制: %d\n", tx.Gas())
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		fmt.Printf("   Gas价格: %s gwei\n", units.FormatGwei(tx.GasPrice()))
+	} else {
+		fmt.Printf("   小费上限: %s gwei\n", units.FormatGwei(tx.GasTipCap()))
+		fmt.Printf("   总费用上限: %s gwei\n", units.FormatGwei(tx.GasFeeCap()))
+	}
+	if len(tx.Data()) > 0 {
+		fmt.Printf("   数据: %x\n", tx.Data())
+	}
+	for _, tuple := range tx.AccessList() {
+		fmt.Printf("   访问列表: %s (%d 个存储槽)\n", tuple.Address.Hex(), len(tuple.StorageKeys))
+	}
+	for _, auth := range tx.SetCodeAuthorizations() {
+		authority := "签名无效"
+		if addr, err := auth.Authority(); err == nil {
+			authority = addr.Hex()
+		}
+		fmt.Printf("   授权: %s 委托给 %s (链ID %s, nonce %d)\n", authority, auth.Address.Hex(), auth.ChainID.Dec(), auth.Nonce)
+	}
+	fmt.Printf("   最坏情况费用: %s ETH\n", units.FormatEther(tx.Cost()))
+}
+
+// waitConfirmed 等待一组相同 nonce 的交易中的某一笔达到指定确认数并打印执行结果，
+// labels 用于说明上链的是哪一笔，交易失败、被替换或丢弃时退出
+func (s *session) waitConfirmed(confirmations uint64, timeout time.Duration, labels map[common.Hash]string, txs ...*types.Transaction) *txutil.Confirmation {
+	fmt.Printf("⏳ 等待 %d 个确认（超时 %s）...\n", confirmations, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	lastSeen := uint64(0)
+	result, err := txutil.WaitConfirmed(ctx, s.client, s.from, txutil.WaitOptions{
+		Confirmations: confirmations,
+		OnProgress: func(tx *types.Transaction, receipt *types.Receipt, n uint64) {
+			if n != lastSeen {
+				lastSeen = n
+				fmt.Printf("   交易 %s 已打包进区块 %d，确认数 %d/%d\n", tx.Hash().Hex(), receipt.BlockNumber.Uint64(), n, confirmations)
+			}
+		},
+	}, txs...)
+	switch {
+	case errors.Is(err, txutil.ErrReplaced):
+		log.Fatal("❌ 交易已被替换: ", err)
+	case errors.Is(err, txutil.ErrDropped):
+		log.Fatal("❌ 交易已被丢弃: ", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Fatal("❌ 等待确认超时，交易可能仍在交易池中: ", err)
+	case err != nil:
+		log.Fatal("❌ 等待交易确认失败:", err)
+	}
+
+	receipt := result.Receipt
+	fmt.Printf("📦 交易回执:\n")
+	if label, ok := labels[result.Tx.Hash()]; ok {
+		fmt.Printf("   上链的交易: %s\n", label)
+	}
+	fmt.Printf("   交易哈希: %s\n", result.Tx.Hash().Hex())
+	fmt.Printf("   区块号: %d\n", receipt.BlockNumber.Uint64())
+	fmt.Printf("   确认数: %d\n", result.Confirmations)
+	fmt.Printf("   Gas使用量: %d\n", receipt.GasUsed)
+	fmt.Printf("   实际Gas价格: %s gwei\n", units.FormatGwei(receipt.EffectiveGasPrice))
+	fmt.Printf("   实际费用: %s ETH\n", units.FormatEther(result.Fee()))
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatal("❌ 交易执行失败: ", s.failureReason(result.Tx, receipt))
+	}
+	fmt.Println("✅ 交易执行成功!")
+	return result
 }
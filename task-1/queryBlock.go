@@ -2,57 +2,347 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
-	"math/big"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"practical-task/config"
+	"practical-task/task-1/blockquery"
 )
 
 func main() {
-	// 定义以太坊节点的 URL
-	url := ""
+	// 命令行参数
+	configPath := flag.String("config", "", "配置文件路径（默认读取 $DAPP_CONFIG 或 ./config.json）")
+	networkName := flag.String("network", "", "使用的网络配置名（默认读取 $DAPP_NETWORK 或配置中的 defaultNetwork）")
+	url := flag.String("rpc", "", "以太坊节点的 URL，覆盖网络配置中的 rpcUrl")
+	wsURL := flag.String("ws", "", "跟踪模式使用的 WebSocket 节点 URL，覆盖网络配置中的 wsUrl（都为空时使用 RPC URL，HTTP 连接会回退为轮询）")
+	ref := flag.String("block", "9135366", "要查询的区块（单区块模式）: 区块号、区块哈希或 latest/safe/finalized/pending/earliest 标签")
+	at := flag.String("at", "", "查询在指定时刻为最新的区块: Unix 时间戳、RFC 3339 或 \"2006-01-02 15:04:05\"（UTC）")
+	start := flag.Uint64("start", 0, "区间扫描的起始区块号")
+	end := flag.Uint64("end", 0, "区间扫描的结束区块号（包含，默认最新区块）")
+	latest := flag.Uint64("latest", 0, "扫描最新的 N 个区块")
+	concurrency := flag.Int("concurrency", 8, "区间扫描的最大并发请求数")
+	stats := flag.Bool("stats", false, "对扫描区间输出统计报告（Gas 使用率、基础费用、优先费、交易类型、blob、转账排行），支持 human 与 json 格式")
+	top := flag.Int("top", 10, "统计报告中发送方/接收方排行榜的长度")
+	cacheDir := flag.String("cache", "", "本地缓存目录，缓存最终确定的区块头、区块体与回执（为空时不缓存）")
+	follow := flag.Bool("follow", false, "持续跟踪新区块并检测链重组")
+	pollInterval := flag.Duration("poll", 12*time.Second, "跟踪模式下无法订阅时的轮询间隔")
+	listTxs := flag.Bool("txs", false, "列出区块中的每一笔交易")
+	receipts := flag.Bool("receipts", false, "获取每笔交易的回执与日志（隐含 -txs）")
+	detail := flag.Bool("detail", false, "输出合并及 Dencun 升级后的详细区块头字段（提款、blob 费用、信标根等）")
+	format := flag.String("format", blockquery.FormatHuman, "输出格式: human、json、ndjson 或 csv（汇总仅出现在 human 与 json 中）")
+	flag.Parse()
+
+	opts := queryOptions{
+		concurrency: *concurrency,
+		txs:         *listTxs || *receipts,
+		receipts:    *receipts,
+		detail:      *detail,
+	}
+
+	// 模式参数互斥，不静默忽略用户指定的参数
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	ranged := *latest > 0 || *start > 0 || *end > 0
+	if *follow && ranged {
+		log.Fatal("跟踪模式不能与 -latest、-start、-end 同时使用")
+	}
+	if *latest > 0 && (*start > 0 || *end > 0) {
+		log.Fatal("-latest 不能与 -start、-end 同时使用")
+	}
+	if (*follow || ranged) && (set["block"] || set["at"]) {
+		log.Fatal("-block 与 -at 只能用于单区块查询，不能与区间扫描或跟踪模式同时使用")
+	}
+	if set["block"] && set["at"] {
+		log.Fatal("-block 与 -at 不能同时使用")
+	}
+	if *stats && !ranged {
+		log.Fatal("-stats 只能用于区间扫描，请使用 -latest 或 -start/-end 指定区间")
+	}
+
+	// 创建输出器，单区块模式下文本格式逐字段打印
+	single := !ranged && !*follow
+	if *follow && *format == blockquery.FormatJSON {
+		log.Fatal("跟踪模式不支持 json 格式，请使用 ndjson")
+	}
+	if *top < 0 {
+		log.Fatal("-top 不能为负数")
+	}
+	if *stats && *format != blockquery.FormatHuman && *format != blockquery.FormatJSON {
+		log.Fatal("统计报告仅支持 human 与 json 格式")
+	}
+	printer, err := blockquery.NewPrinter(*format, os.Stdout, blockquery.PrinterOptions{
+		Verbose:      single,
+		Transactions: opts.txs,
+	})
+	if err != nil {
+		log.Fatal("创建输出器失败:", err)
+	}
+
+	// 读取网络配置，命令行参数优先
+	_, network, err := config.LoadNetwork(*configPath, *networkName)
+	if err != nil {
+		log.Fatal("读取配置失败:", err)
+	}
+	if *url != "" {
+		network.RPCURL = *url
+	}
+	if *wsURL != "" {
+		network.WSURL = *wsURL
+	}
+	endpoint, err := network.Endpoint()
+	if err != nil {
+		log.Fatal("读取配置失败:", err)
+	}
 
 	// 连接到以太坊客户端
-	client, err := ethclient.Dial(url)
+	client, err := ethclient.Dial(endpoint)
 	if err != nil {
 		log.Fatal("连接以太坊客户端失败:", err)
 	}
 
-	// 指定要查询的区块号
-	blockNumber := big.NewInt(9135366)
+	// 详细区块头需要链配置来计算 blob 基础费用，缓存按链ID分目录
+	var backend blockquery.Backend = client
+	if opts.detail || *cacheDir != "" {
+		chainID, err := client.ChainID(context.Background())
+		if err != nil {
+			log.Fatal("获取链ID失败:", err)
+		}
+		if opts.detail {
+			opts.chainConfig = blockquery.ChainConfig(chainID)
+			if opts.chainConfig == nil {
+				fmt.Fprintf(os.Stderr, "⚠️ 未知的链ID %s，无法计算 blob 基础费用\n", chainID)
+			}
+		}
+		if *cacheDir != "" {
+			cache, err := blockquery.OpenCache(context.Background(), client, *cacheDir, chainID)
+			if err != nil {
+				log.Fatal("打开本地缓存失败:", err)
+			}
+			defer func() {
+				hits, misses := cache.Stats()
+				fmt.Fprintf(os.Stderr, "💾 缓存命中 %d 次，未命中 %d 次（最终确定区块 %d）\n", hits, misses, cache.Finalized())
+			}()
+			backend = cache
+		}
+	}
 
-	// 获取区块头信息
-	header, err := client.HeaderByNumber(context.Background(), blockNumber)
-	if err != nil {
-		log.Fatal("获取区块头失败:", err)
+	switch {
+	case *follow:
+		if network.WSURL != "" {
+			client, err = ethclient.Dial(network.WSURL)
+			if err != nil {
+				log.Fatal("连接 WebSocket 节点失败:", err)
+			}
+		}
+		followHeads(client, printer, *pollInterval, opts)
+	case *latest > 0:
+		from, to, err := blockquery.LatestRange(context.Background(), backend, *latest)
+		if err != nil {
+			log.Fatal("计算区块区间失败:", err)
+		}
+		if *stats {
+			reportStats(backend, *format, from, to, *top, opts)
+			return
+		}
+		scanRange(backend, printer, from, to, opts)
+	case *start > 0 || *end > 0:
+		to := *end
+		if to == 0 {
+			head, err := client.BlockNumber(context.Background())
+			if err != nil {
+				log.Fatal("获取最新区块号失败:", err)
+			}
+			to = head
+		}
+		if *stats {
+			reportStats(backend, *format, *start, to, *top, opts)
+			return
+		}
+		scanRange(backend, printer, *start, to, opts)
+	default:
+		blockRef, err := resolveRef(backend, *ref, *at)
+		if err != nil {
+			log.Fatal("解析区块引用失败:", err)
+		}
+		queryBlock(backend, printer, blockRef, opts)
+	}
+
+	if err := printer.Flush(); err != nil {
+		log.Fatal("写出结果失败:", err)
 	}
+}
 
-	// 打印区块头信息
-	fmt.Println("区块头编号:", header.Number.Uint64())     // 区块号
-	fmt.Println("区块头时间戳:", header.Time)               // 区块生成时间
-	fmt.Println("区块头难度:", header.Difficulty.Uint64()) // 挖矿难度（通常为 0，因为使用 PoS）
-	fmt.Println("区块头哈希:", header.Hash().Hex())        // 区块头的哈希值
+// 查询选项
+type queryOptions struct {
+	concurrency int  // 最大并发请求数
+	txs         bool // 是否列出交易
+	receipts    bool // 是否获取交易回执
+	detail      bool // 是否输出详细区块头
 
+	chainConfig *params.ChainConfig // 链配置，用于计算 blob 基础费用
+}
+
+// 查询单个区块并按选定格式输出
+func queryBlock(backend blockquery.Backend, printer blockquery.Printer, ref blockquery.BlockRef, opts queryOptions) {
 	// 获取完整区块信息
-	block, err := client.BlockByNumber(context.Background(), blockNumber)
+	block, err := blockquery.BlockByRef(context.Background(), backend, ref)
 	if err != nil {
 		log.Fatal("获取区块失败:", err)
 	}
 
-	// 打印区块信息
-	fmt.Println("区块编号:", block.Number().Uint64())     // 区块号
-	fmt.Println("区块时间戳:", block.Time())               // 区块生成时间
-	fmt.Println("区块难度:", block.Difficulty().Uint64()) // 挖矿难度
-	fmt.Println("区块哈希:", block.Hash().Hex())          // 区块哈希值
-	fmt.Println("交易数量:", len(block.Transactions()))   // 区块中包含的交易数
+	info, err := newBlockInfo(backend, block, opts)
+	if err != nil {
+		log.Fatal("解析区块交易失败:", err)
+	}
+
+	// 输出区块信息
+	if err := printer.PrintBlock(info); err != nil {
+		log.Fatal("输出区块信息失败:", err)
+	}
+}
 
-	// 获取区块中的交易数量
-	count, err := client.TransactionCount(context.Background(), block.Hash())
+// 解析单区块模式要查询的区块；指定了时刻时通过二分查找定位区块
+func resolveRef(backend blockquery.Backend, ref, at string) (blockquery.BlockRef, error) {
+	if at == "" {
+		return blockquery.ParseBlockRef(ref)
+	}
+	t, err := blockquery.ParseTime(at)
 	if err != nil {
-		log.Fatal("获取交易数量失败:", err)
+		return blockquery.BlockRef{}, err
 	}
+	header, err := blockquery.HeaderAtTime(context.Background(), backend, t)
+	if err != nil {
+		return blockquery.BlockRef{}, err
+	}
+	fmt.Fprintf(os.Stderr, "🕒 %s 时的最新区块为 %d（时间戳 %d）\n",
+		t.UTC().Format(time.RFC3339), header.Number.Uint64(), header.Time)
+	hash := header.Hash()
+	return blockquery.BlockRef{Hash: &hash}, nil
+}
 
-	// 打印交易数量
-	fmt.Println("交易总数:", count) // 交易数量
+// 并发扫描 [start, end] 区间内的区块，逐块输出信息并输出汇总
+func scanRange(backend blockquery.Backend, printer blockquery.Printer, start, end uint64, opts queryOptions) {
+	fmt.Fprintf(os.Stderr, "正在扫描区块 %d - %d（并发数 %d）...\n", start, end, opts.concurrency)
+
+	var totals blockquery.Totals
+	err := blockquery.ScanRange(context.Background(), backend, start, end, opts.concurrency, func(block *types.Block) error {
+		info, err := newBlockInfo(backend, block, opts)
+		if err != nil {
+			return err
+		}
+		totals.Add(info)
+		return printer.PrintBlock(info)
+	})
+	if err != nil {
+		log.Fatal("扫描区块失败:", err)
+	}
+
+	// 输出汇总信息
+	if err := printer.PrintTotals(&totals); err != nil {
+		log.Fatal("输出汇总信息失败:", err)
+	}
+}
+
+// 扫描 [start, end] 区间并输出统计报告
+func reportStats(backend blockquery.Backend, format string, start, end uint64, top int, opts queryOptions) {
+	fmt.Fprintf(os.Stderr, "正在统计区块 %d - %d（并发数 %d）...\n", start, end, opts.concurrency)
+
+	collector := blockquery.NewStatsCollector(top)
+	err := blockquery.ScanRange(context.Background(), backend, start, end, opts.concurrency, collector.Add)
+	if err != nil {
+		log.Fatal("扫描区块失败:", err)
+	}
+	stats := collector.Result()
+
+	if format == blockquery.FormatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(stats)
+	} else {
+		err = blockquery.WriteStatsTable(os.Stdout, stats)
+	}
+	if err != nil {
+		log.Fatal("输出统计报告失败:", err)
+	}
+}
+
+// 持续跟踪新区块并逐块输出，直到收到中断信号；检测到链重组时输出被替换和新加入的区块
+func followHeads(client *ethclient.Client, printer blockquery.Printer, pollInterval time.Duration, opts queryOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	follower := blockquery.NewFollower(client)
+	follower.PollInterval = pollInterval
+	follower.OnNotice = func(msg string) {
+		fmt.Fprintln(os.Stderr, "ℹ️", msg)
+	}
+	follower.OnReorg = func(reorg *blockquery.Reorg) error {
+		fmt.Fprintf(os.Stderr, "⚠️ 检测到链重组，深度 %d", reorg.Depth())
+		if reorg.Ancestor != nil {
+			fmt.Fprintf(os.Stderr, "，共同祖先区块 %d (%s)", reorg.Ancestor.Number.Uint64(), reorg.Ancestor.Hash().Hex())
+		} else {
+			fmt.Fprint(os.Stderr, "，超出跟踪范围，无法确定共同祖先")
+		}
+		fmt.Fprintln(os.Stderr)
+		for _, header := range reorg.Replaced {
+			fmt.Fprintf(os.Stderr, "   - 被替换: 区块 %d %s\n", header.Number.Uint64(), header.Hash().Hex())
+		}
+		for _, header := range reorg.Added {
+			fmt.Fprintf(os.Stderr, "   + 新加入: 区块 %d %s\n", header.Number.Uint64(), header.Hash().Hex())
+		}
+		return nil
+	}
+	follower.OnBlock = func(header *types.Header) error {
+		block, err := client.BlockByHash(ctx, header.Hash())
+		if err != nil {
+			return fmt.Errorf("获取区块 %d 失败: %w", header.Number.Uint64(), err)
+		}
+		info, err := newBlockInfo(client, block, opts)
+		if err != nil {
+			return err
+		}
+		return printer.PrintBlock(info)
+	}
+
+	fmt.Fprintln(os.Stderr, "正在跟踪新区块，按 Ctrl+C 退出...")
+	if err := follower.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatal("跟踪新区块失败:", err)
+	}
+}
+
+// 构造区块输出信息，按需解析交易列表并关联回执
+func newBlockInfo(backend blockquery.Backend, block *types.Block, opts queryOptions) (*blockquery.BlockInfo, error) {
+	info := blockquery.NewBlockInfo(block)
+	if opts.detail {
+		info.Detail = blockquery.NewHeaderDetail(block, opts.chainConfig)
+	}
+	if !opts.txs {
+		return info, nil
+	}
+	txs, err := blockquery.NewTxInfos(block)
+	if err != nil {
+		return nil, err
+	}
+	info.Transactions = txs
+
+	if opts.receipts {
+		receipts, err := blockquery.FetchReceipts(context.Background(), backend, block, opts.concurrency)
+		if err != nil {
+			return nil, err
+		}
+		failed, err := blockquery.AttachReceipts(txs, receipts)
+		if err != nil {
+			return nil, err
+		}
+		info.FailedTxs = &failed
+	}
+	return info, nil
 }
@@ -2,47 +2,98 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"practical-task/config"
+	"practical-task/keysource"
+	"practical-task/nonce"
 	"practical-task/task-2/counter"
+	"practical-task/txutil"
+	"practical-task/units"
 )
 
 func main() {
-	// 连接到以太坊Sepolia测试网络
-	fmt.Println("正在连接到以太坊Sepolia测试网络...")
-	url := "https://sepolia.infura.io/v3/4e00451dd920412090191a4315760504"
+	// 命令行参数
+	configPath := flag.String("config", "", "配置文件路径（默认读取 $DAPP_CONFIG 或 ./config.json）")
+	networkName := flag.String("network", "", "使用的网络配置名（默认读取 $DAPP_NETWORK 或配置中的 defaultNetwork）")
+	account := flag.String("account", "", "使用的账户名，对应配置中 accounts 的密钥来源")
+	keySpec := flag.String("key", "", "私钥来源，优先于 -account: keystore:<路径>、env:<变量名> 或 file:<路径>")
+	allowChainMismatch := flag.Bool("allow-chain-mismatch", false, "节点链ID与网络配置不一致时仍然签名并发送（危险）")
+	gasMultiplier := flag.Float64("gas-multiplier", 0, "Gas 估算值的安全系数，覆盖网络配置（默认 1.2）")
+	gasCap := flag.Uint64("gas-cap", 0, "Gas 限制的上限，覆盖网络配置（0 表示使用配置）")
+	contractAddr := flag.String("contract", "", "已部署的 Counter 合约地址，设置后跳过部署直接调用")
+	method := flag.String("method", "increment", "要调用的合约方法: increment、reset 或 setCount")
+	newCount := flag.Uint64("count", 0, "setCount 设置的计数值")
+	accessListSpec := flag.String("access-list", "", "合约调用的 EIP-2930 访问列表: auto 通过 eth_createAccessList 生成并在能节省 Gas 时使用，"+
+		"或访问列表 JSON 文件路径；为空时不使用")
+	simulateFlag := flag.Bool("simulate", false, "发送前在待处理区块上模拟执行交易，执行失败时不发送")
+	dryRun := flag.Bool("dry-run", false, "只模拟执行交易，不发送（未指定 -contract 时只模拟部署）")
+	flag.Parse()
+
+	label, ok := methodLabels[*method]
+	if !ok {
+		log.Fatalf("❌ 未知的合约方法 %q，可用方法: increment、reset、setCount", *method)
+	}
+	if *contractAddr != "" && !common.IsHexAddress(*contractAddr) {
+		log.Fatalf("❌ 无效的合约地址 %q", *contractAddr)
+	}
+	simulating := *simulateFlag || *dryRun
+
+	// 读取网络配置
+	cfg, network, err := config.LoadNetwork(*configPath, *networkName)
+	if err != nil {
+		log.Fatal("❌ 读取配置失败:", err)
+	}
+	url, err := network.Endpoint()
+	if err != nil {
+		log.Fatal("❌ 读取配置失败:", err)
+	}
+	if *gasMultiplier > 0 {
+		network.Gas.LimitMultiplier = *gasMultiplier
+	}
+	if *gasCap > 0 {
+		network.Gas.LimitCap = *gasCap
+	}
+
+	// 连接到以太坊网络
+	fmt.Printf("正在连接到以太坊网络 %s...\n", network.Name)
 	client, err := ethclient.Dial(url)
 	if err != nil {
 		log.Fatal("❌ 连接网络失败:", err)
 	}
 	fmt.Println("✅ 网络连接成功")
 
-	// 从私钥获取ECDSA私钥对象
-	fmt.Println("正在解析私钥...")
-	privateKey, err := crypto.HexToECDSA("")
-	if err != nil {
-		log.Fatal("❌ 解析私钥失败:", err)
+	// 确定密钥来源：-key 优先，其次是配置中的账户，最后是默认的环境变量
+	spec := *keySpec
+	if spec == "" {
+		if spec, err = cfg.Account(*account); err != nil {
+			log.Fatal("❌ 读取账户配置失败:", err)
+		}
+	}
+	if spec == "" {
+		spec = keysource.DefaultSpec
 	}
-	fmt.Println("✅ 私钥解析成功")
 
-	// 从私钥获取公钥
-	fmt.Println("正在从私钥提取公钥...")
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("❌ 公钥类型断言失败: publicKey不是*ecdsa.PublicKey类型")
+	// 从密钥来源加载私钥（keystore 文件、环境变量或权限受限的文件）
+	fmt.Println("正在加载私钥...")
+	key, err := keysource.Load(spec)
+	if err != nil {
+		log.Fatal("❌ 加载私钥失败:", err)
 	}
-	fmt.Println("✅ 公钥提取成功")
+	fmt.Printf("✅ 私钥加载成功（来源: %s）\n", key.Source)
 
-	// 从公钥获取发送方地址
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	// 获取部署者地址
+	fromAddress := key.Address
 	fmt.Printf("�� 部署者地址: %s\n", fromAddress.Hex())
 
 	// 检查账户余额
@@ -50,15 +101,10 @@ func main() {
 	if err != nil {
 		log.Fatal("❌ 获取账户余额失败:", err)
 	}
-	fmt.Printf("💰 账户余额: %s ETH\n", weiToEther(balance).String())
+	fmt.Printf("💰 账户余额: %s ETH\n", units.FormatEther(balance))
 
-	// 获取发送方地址的nonce值（交易序号）
-	fmt.Printf("正在获取地址 %s 的nonce值...\n", fromAddress.Hex())
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		log.Fatal("❌ 获取nonce失败:", err)
-	}
-	fmt.Printf("✅ nonce值获取成功: %d\n", nonce)
+	// 由nonce管理器在本地分配nonce，部署与调用依次使用
+	nonces := nonce.NewManager(client)
 
 	// 获取建议的Gas价格
 	fmt.Println("正在获取建议的Gas价格...")
@@ -66,65 +112,136 @@ func main() {
 	if err != nil {
 		log.Fatal("❌ 获取Gas价格失败:", err)
 	}
-	fmt.Printf("✅ Gas价格获取成功: %s wei\n", gasPrice.String())
+	fmt.Printf("✅ Gas价格获取成功: %s gwei\n", units.FormatGwei(gasPrice))
+	if maxPrice := network.Gas.GasFeeCap; maxPrice != nil && gasPrice.Cmp(maxPrice) > 0 {
+		gasPrice = maxPrice
+		fmt.Printf("⚠️ Gas价格超过配置上限，使用上限: %s gwei\n", units.FormatGwei(gasPrice))
+	}
 
-	// 获取网络链ID
+	// 获取网络链ID并与配置中的预期链ID核对，避免把交易签到错误的链上
 	fmt.Println("正在获取网络链ID...")
-	chainId, err := client.NetworkID(context.Background())
+	chainId, err := txutil.VerifyChainID(context.Background(), client, network.ExpectedChainID())
 	if err != nil {
-		log.Fatal("❌ 获取链ID失败:", err)
+		if chainId == nil {
+			log.Fatal("❌ 获取链ID失败:", err)
+		}
+		if !*allowChainMismatch {
+			log.Fatal("❌ 链ID校验失败: ", err, "（确认无误后可使用 -allow-chain-mismatch 跳过校验）")
+		}
+		fmt.Printf("⚠️ 链ID校验失败，已按 -allow-chain-mismatch 继续: %v\n", err)
 	}
 	fmt.Printf("✅ 链ID获取成功: %s\n", chainId.String())
 
 	// 创建交易授权对象
 	fmt.Println("正在创建交易授权对象...")
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+	auth, err := key.TransactOpts(chainId)
 	if err != nil {
 		log.Fatal("❌ 创建交易授权对象失败:", err)
 	}
 
-	// 设置交易参数
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)     // in wei
-	auth.GasLimit = uint64(500000) // 增加Gas限制
+	// 设置交易参数，Gas限制在估算后设置
+	auth.Value = big.NewInt(0) // in wei
 	auth.GasPrice = gasPrice
 	fmt.Println("✅ 交易授权对象创建成功")
-	fmt.Printf("🔧 交易参数设置:\n")
-	fmt.Printf("   Nonce: %d\n", auth.Nonce)
-	fmt.Printf("   Value: %s wei\n", auth.Value.String())
-	fmt.Printf("   GasLimit: %d\n", auth.GasLimit)
-	fmt.Printf("   GasPrice: %s wei\n", auth.GasPrice.String())
 
-	// 部署Counter合约
-	fmt.Println("正在部署Counter智能合约...")
-	address, tx, instance, err := counter.DeployCounter(auth, client)
+	counterABI, err := counter.CounterMetaData.GetAbi()
 	if err != nil {
-		log.Fatal("❌ 合约部署失败:", err)
+		log.Fatal("❌ 解析合约ABI失败:", err)
 	}
-	fmt.Println("✅ 合约部署交易已提交")
-
-	// 打印合约部署信息
-	fmt.Println("========== 合约部署信息 ==========")
-	fmt.Printf("📄 合约地址: %s\n", address.Hex())
-	fmt.Printf("🔗 交易哈希: %s\n", tx.Hash().Hex())
-	fmt.Println("=================================")
-
-	// 等待交易确认
-	fmt.Println("⏳ 等待交易确认...")
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if err != nil {
-		log.Fatal("❌ 等待交易确认失败:", err)
-	}
+	var (
+		address  common.Address
+		tx       *types.Transaction
+		instance *counter.Counter
+		receipt  *types.Receipt
+	)
+	if *contractAddr == "" {
+		deployData := common.FromHex(counter.CounterMetaData.Bin)
 
-	if receipt.Status == 1 {
-		fmt.Println("✅ 合约部署成功!")
-		fmt.Printf("📦 区块号: %d\n", receipt.BlockNumber.Uint64())
-		fmt.Printf("⛽ Gas使用量: %d\n", receipt.GasUsed)
+		// 估算部署合约所需的Gas
+		fmt.Println("正在估算部署Gas用量...")
+		estimate, err := txutil.EstimateGasLimit(context.Background(), client, ethereum.CallMsg{
+			From: fromAddress,
+			Data: deployData,
+		}, network.Gas.LimitMultiplier, network.Gas.LimitCap)
+		if err != nil {
+			if execErr := txutil.ExecutionError(err, counterABI); execErr != nil {
+				log.Fatal("❌ 部署交易会执行失败，交易不会发送: ", execErr)
+			}
+			log.Fatal("❌ 估算部署Gas失败:", err)
+		}
+		fmt.Printf("⛽ 部署Gas: %s\n", estimate)
+
+		// 设置交易参数
+		auth.GasLimit = estimate.Limit
+
+		// 以实际发送的参数模拟执行部署交易
+		if simulating {
+			simulate(client, auth, nil, deployData, nil, counterABI)
+			if *dryRun {
+				fmt.Println("🧪 已按 -dry-run 只模拟部署，未发送交易")
+				return
+			}
+		}
+		checkBalance(client, fromAddress, auth)
+		fmt.Printf("🔧 交易参数设置:\n")
+		fmt.Printf("   Value: %s ETH\n", units.FormatEther(auth.Value))
+		fmt.Printf("   GasLimit: %d\n", auth.GasLimit)
+		fmt.Printf("   GasPrice: %s gwei\n", units.FormatGwei(auth.GasPrice))
+
+		// 部署Counter合约
+		fmt.Println("正在部署Counter智能合约...")
+		_, err = nonces.Submit(context.Background(), fromAddress, func(n uint64) error {
+			fmt.Printf("   Nonce: %d\n", n)
+			auth.Nonce = new(big.Int).SetUint64(n)
+			address, tx, instance, err = counter.DeployCounter(auth, client)
+			return err
+		})
+		if err != nil {
+			log.Fatal("❌ 合约部署失败:", err)
+		}
+		fmt.Println("✅ 合约部署交易已提交")
+
+		// 打印合约部署信息
+		fmt.Println("========== 合约部署信息 ==========")
+		fmt.Printf("📄 合约地址: %s\n", address.Hex())
+		fmt.Printf("🔗 交易哈希: %s\n", tx.Hash().Hex())
+		if link := network.AddressURL(address); link != "" {
+			fmt.Printf("🌐 区块浏览器: %s\n", link)
+		}
+		fmt.Println("=================================")
+
+		// 等待交易确认
+		fmt.Println("⏳ 等待交易确认...")
+		receipt, err = bind.WaitMined(ctx, client, tx)
+		if err != nil {
+			log.Fatal("❌ 等待交易确认失败:", err)
+		}
+
+		if receipt.Status == 1 {
+			fmt.Println("✅ 合约部署成功!")
+			fmt.Printf("📦 区块号: %d\n", receipt.BlockNumber.Uint64())
+			fmt.Printf("⛽ Gas使用量: %d\n", receipt.GasUsed)
+		} else {
+			log.Fatal("❌ 合约部署失败: ", failureReason(client, fromAddress, tx, receipt, counterABI))
+		}
 	} else {
-		log.Fatal("❌ 合约部署失败: 交易被回滚")
+		// 使用已部署的合约
+		address = common.HexToAddress(*contractAddr)
+		code, err := client.CodeAt(context.Background(), address, nil)
+		if err != nil {
+			log.Fatal("❌ 获取合约代码失败:", err)
+		}
+		if len(code) == 0 {
+			log.Fatalf("❌ 地址 %s 上没有合约代码", address.Hex())
+		}
+		instance, err = counter.NewCounter(address, client)
+		if err != nil {
+			log.Fatal("❌ 绑定合约失败:", err)
+		}
+		fmt.Printf("📄 使用已部署的合约: %s\n", address.Hex())
 	}
 
 	// 测试合约功能
@@ -137,25 +254,88 @@ func main() {
 	}
 	fmt.Printf("�� 初始计数: %s\n", count.String())
 
-	// 增加计数
-	fmt.Println("正在增加计数...")
-	auth.Nonce = big.NewInt(int64(nonce + 1))
-	auth.GasLimit = uint64(100000)
+	// 调用合约方法
+	fmt.Printf("正在%s...\n", label)
+	var params []interface{}
+	if *method == "setCount" {
+		params = append(params, new(big.Int).SetUint64(*newCount))
+	}
+	input, err := counterABI.Pack(*method, params...)
+	if err != nil {
+		log.Fatal("❌ 编码调用数据失败:", err)
+	}
 
-	tx, err = instance.Increment(auth)
+	callMsg := ethereum.CallMsg{
+		From: fromAddress,
+		To:   &address,
+		Data: input,
+	}
+	if *accessListSpec != "" {
+		callMsg.AccessList = accessList(client, *accessListSpec, callMsg)
+	}
+	estimate, err := txutil.EstimateGasLimit(context.Background(), client, callMsg, network.Gas.LimitMultiplier, network.Gas.LimitCap)
 	if err != nil {
-		log.Fatal("❌ 增加计数失败:", err)
+		// 非合约所有者调用 reset、setCount 时在估算阶段就会回滚，按合约ABI解码回滚原因
+		if execErr := txutil.ExecutionError(err, counterABI); execErr != nil {
+			log.Fatalf("❌ %s会执行失败，交易不会发送: %v", label, execErr)
+		}
+		log.Fatalf("❌ 估算%sGas失败: %v", label, err)
+	}
+	fmt.Printf("⛽ %sGas: %s\n", label, estimate)
+	auth.GasLimit = estimate.Limit
+
+	// 以确定的访问列表与Gas限制模拟执行调用交易，自定义错误按合约ABI解码
+	if simulating {
+		simulate(client, auth, &address, input, callMsg.AccessList, counterABI)
+		if *dryRun {
+			fmt.Println("🧪 已按 -dry-run 只模拟调用，未发送交易")
+			return
+		}
+	}
+	checkBalance(client, fromAddress, auth)
+
+	_, err = nonces.Submit(context.Background(), fromAddress, func(n uint64) error {
+		auth.Nonce = new(big.Int).SetUint64(n)
+		if len(callMsg.AccessList) > 0 {
+			// 绑定代码的 TransactOpts 不支持访问列表，需要手动创建并签名访问列表交易
+			tx, err = key.SignTx(types.NewTx(&types.AccessListTx{
+				ChainID:    chainId,
+				Nonce:      n,
+				GasPrice:   auth.GasPrice,
+				Gas:        auth.GasLimit,
+				To:         &address,
+				Value:      auth.Value,
+				Data:       input,
+				AccessList: callMsg.AccessList,
+			}), types.LatestSignerForChainID(chainId))
+			if err != nil {
+				return err
+			}
+			return client.SendTransaction(context.Background(), tx)
+		}
+		switch *method {
+		case "reset":
+			tx, err = instance.Reset(auth)
+		case "setCount":
+			tx, err = instance.SetCount(auth, new(big.Int).SetUint64(*newCount))
+		default:
+			tx, err = instance.Increment(auth)
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatalf("❌ %s失败: %v", label, err)
 	}
-	fmt.Printf("🔗 增加计数交易哈希: %s\n", tx.Hash().Hex())
+	fmt.Printf("🔗 %s交易哈希: %s\n", label, tx.Hash().Hex())
 
 	// 等待交易确认
 	receipt, err = bind.WaitMined(ctx, client, tx)
 	if err != nil {
-		log.Fatal("❌ 等待增加计数交易确认失败:", err)
+		log.Fatalf("❌ 等待%s交易确认失败: %v", label, err)
 	}
 
 	if receipt.Status == 1 {
-		fmt.Println("✅ 计数增加成功!")
+		fmt.Printf("✅ %s成功!\n", label)
 
 		// 获取新的计数
 		count, err = instance.GetCount(nil)
@@ -164,16 +344,87 @@ func main() {
 		}
 		fmt.Printf("📊 新计数: %s\n", count.String())
 	} else {
-		log.Fatal("❌ 增加计数失败: 交易被回滚")
+		log.Fatalf("❌ %s失败: %s", label, failureReason(client, fromAddress, tx, receipt, counterABI))
 	}
 
 	fmt.Println("🎉 所有操作完成!")
 }
 
-// 将Wei转换为Ether的辅助函数
-func weiToEther(wei *big.Int) *big.Float {
-	ether := new(big.Float)
-	ether.SetString(wei.String())
-	etherFloat := new(big.Float).Quo(ether, big.NewFloat(1e18))
-	return etherFloat
+// methodLabels 是可调用的合约方法及其说明
+var methodLabels = map[string]string{
+	"increment": "增加计数",
+	"reset":     "重置计数",
+	"setCount":  "设置计数",
+}
+
+// accessList 按 -access-list 参数准备合约调用的访问列表并打印与不带访问列表时的 Gas 对比。
+// auto 生成的访问列表不能节省 Gas 时不使用；返回 nil 表示不使用访问列表
+func accessList(client *ethclient.Client, spec string, msg ethereum.CallMsg) types.AccessList {
+	ctx := context.Background()
+	var (
+		result *txutil.AccessListResult
+		err    error
+	)
+	if spec == "auto" {
+		fmt.Println("正在通过 eth_createAccessList 生成访问列表...")
+		result, err = txutil.CreateAccessList(ctx, client.Client(), msg)
+	} else {
+		fmt.Printf("正在读取访问列表 %s...\n", spec)
+		var list types.AccessList
+		if list, err = txutil.LoadAccessList(spec); err == nil {
+			result, err = txutil.CompareAccessList(ctx, client, msg, list)
+		}
+	}
+	if err != nil {
+		log.Fatal("❌ 准备访问列表失败:", err)
+	}
+	fmt.Printf("📋 访问列表: %s\n", result)
+	if spec == "auto" && result.Saved() <= 0 {
+		fmt.Println("⚠️ 访问列表不能节省Gas，不使用访问列表")
+		return nil
+	}
+	return result.AccessList
+}
+
+// simulate 以 eth_call 在待处理区块上模拟执行与将要发送的交易完全相同的调用（Gas限制、价格、访问列表）
+// 并打印结果，执行失败时退出。to 为 nil 表示合约部署，调用前需先设置 auth.GasLimit
+func simulate(client *ethclient.Client, auth *bind.TransactOpts, to *common.Address, data []byte, accessList types.AccessList, contract *abi.ABI) {
+	fmt.Println("🧪 正在模拟执行交易...")
+	result, err := txutil.Simulate(context.Background(), client, ethereum.CallMsg{
+		From:       auth.From,
+		To:         to,
+		Gas:        auth.GasLimit,
+		GasPrice:   auth.GasPrice,
+		Value:      auth.Value,
+		Data:       data,
+		AccessList: accessList,
+	}, contract)
+	if err != nil {
+		log.Fatal("❌ 模拟执行失败:", err)
+	}
+	if result.Err != nil {
+		log.Fatal("❌ 模拟执行失败，交易不会发送: ", result.Err)
+	}
+	fmt.Printf("✅ 模拟执行成功: Gas用量 %d (Gas限制 %d)\n", result.GasUsed, auth.GasLimit)
+}
+
+// failureReason 在父区块状态上重放执行失败的交易，按合约ABI解码并返回失败原因的说明，
+// 例如非合约所有者调用 reset、setCount 时的 "Only owner can call this function"
+func failureReason(client *ethclient.Client, from common.Address, tx *types.Transaction, receipt *types.Receipt, contract *abi.ABI) string {
+	fmt.Println("🔍 正在重放失败的交易以获取失败原因...")
+	reason, err := txutil.ReplayFailure(context.Background(), client, from, tx, receipt, contract)
+	if err != nil {
+		return fmt.Sprintf("交易被回滚（重放交易失败: %v）", err)
+	}
+	return reason.Error()
+}
+
+// checkBalance 在签名前确认余额足以支付交易的最坏情况费用，不足时退出
+func checkBalance(client *ethclient.Client, from common.Address, auth *bind.TransactOpts) {
+	cost := txutil.MaxCost(auth.Value, auth.GasLimit, auth.GasPrice, 0, nil)
+	balance, err := txutil.CheckBalance(context.Background(), client, from, cost)
+	if err != nil {
+		log.Fatal("❌ 余额检查失败:", err)
+	}
+	fmt.Printf("✅ 余额充足: 余额 %s ETH, 最坏情况费用 %s ETH\n", units.FormatEther(balance), units.FormatEther(cost))
 }
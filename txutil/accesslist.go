@@ -0,0 +1,80 @@
+package txutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// AccessListResult 是 EIP-2930 访问列表及带与不带访问列表时估算的 Gas 用量。
+// 访问列表中的每个地址和存储槽都要预先付费，访问合约本身等已预热的地址时反而会多用 Gas
+type AccessListResult struct {
+	AccessList types.AccessList
+	GasWithout uint64 // 不带访问列表时估算的 Gas 用量
+	GasWith    uint64 // 带访问列表时估算的 Gas 用量
+}
+
+// Saved 返回访问列表节省的 Gas，负数表示多用的 Gas
+func (r *AccessListResult) Saved() int64 {
+	return int64(r.GasWithout) - int64(r.GasWith)
+}
+
+// String 返回访问列表的大小与 Gas 对比
+func (r *AccessListResult) String() string {
+	return fmt.Sprintf("%d 个地址, %d 个存储槽; Gas 用量 %d -> %d, 节省 %d",
+		len(r.AccessList), r.AccessList.StorageKeys(), r.GasWithout, r.GasWith, r.Saved())
+}
+
+// LoadAccessList 读取 JSON 格式的访问列表文件，格式与 eth_createAccessList 返回的 accessList 相同：
+// [{"address": "0x...", "storageKeys": ["0x..."]}]，没有存储槽的地址也必须写出空的 storageKeys
+func LoadAccessList(path string) (types.AccessList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取访问列表文件失败: %w", err)
+	}
+	var list types.AccessList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("解析访问列表文件 %s 失败: %w", path, err)
+	}
+	return list, nil
+}
+
+// CreateAccessList 通过 eth_createAccessList 在最新区块（latest）上生成交易的访问列表，并比较带与不带
+// 访问列表的 Gas 用量。不包含交易池中待处理交易造成的状态变化；节点不支持该方法时返回错误，
+// msg 中的 Gas 与 AccessList 会被忽略
+func CreateAccessList(ctx context.Context, client *rpc.Client, msg ethereum.CallMsg) (*AccessListResult, error) {
+	msg.Gas, msg.AccessList = 0, nil
+	list, _, vmErr, err := gethclient.New(client).CreateAccessList(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("生成访问列表失败（节点可能不支持 eth_createAccessList）: %w", err)
+	}
+	if vmErr != "" {
+		return nil, fmt.Errorf("生成访问列表时交易执行失败: %s", vmErr)
+	}
+	if list == nil {
+		list = &types.AccessList{}
+	}
+	return CompareAccessList(ctx, ethclient.NewClient(client), msg, *list)
+}
+
+// CompareAccessList 在最新区块（latest）上分别估算不带与带访问列表时交易的 Gas 用量
+func CompareAccessList(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, list types.AccessList) (*AccessListResult, error) {
+	msg.Gas, msg.AccessList = 0, nil
+	without, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("估算不带访问列表的Gas失败: %w", err)
+	}
+	msg.AccessList = list
+	with, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("估算带访问列表的Gas失败: %w", err)
+	}
+	return &AccessListResult{AccessList: list, GasWithout: without, GasWith: with}, nil
+}
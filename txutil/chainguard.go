@@ -0,0 +1,40 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrNoExpectedChainID 表示网络配置中没有设置预期的链 ID，无法确认节点连接的是哪条链
+var ErrNoExpectedChainID = errors.New("网络配置未设置 chainId，无法确认节点所在的链")
+
+// ChainMismatchError 表示节点返回的链 ID 与网络配置中的预期链 ID 不一致
+type ChainMismatchError struct {
+	Expected *big.Int
+	Actual   *big.Int
+}
+
+func (e *ChainMismatchError) Error() string {
+	return fmt.Sprintf("节点链ID %s 与配置的预期链ID %s 不一致", e.Actual, e.Expected)
+}
+
+// VerifyChainID 查询节点的链 ID 并与预期链 ID 比较。查询成功时总是返回节点的链 ID，
+// 不一致时同时返回 *ChainMismatchError，expected 为 nil 时返回 ErrNoExpectedChainID，
+// 调用方可以在明确允许时忽略这两种错误继续签名
+func VerifyChainID(ctx context.Context, client *ethclient.Client, expected *big.Int) (*big.Int, error) {
+	actual, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if expected == nil {
+		return actual, ErrNoExpectedChainID
+	}
+	if actual.Cmp(expected) != 0 {
+		return actual, &ChainMismatchError{Expected: expected, Actual: actual}
+	}
+	return actual, nil
+}
@@ -0,0 +1,182 @@
+package txutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Envelope 是未签名交易的 JSON 信封：在联网机器上填好 nonce、费用和链 ID，
+// 拷贝到离线机器上签名，签名时不需要访问节点。金额与费用的单位均为 wei
+type Envelope struct {
+	Type       uint8            `json:"type"` // 0 为 legacy 交易，1 为访问列表交易，2 为 EIP-1559 交易
+	ChainID    *big.Int         `json:"chainId"`
+	From       common.Address   `json:"from"` // 预期的签名账户，签名时核对
+	Nonce      uint64           `json:"nonce"`
+	To         *common.Address  `json:"to"`
+	Value      *big.Int         `json:"value"`
+	Gas        uint64           `json:"gas"`
+	GasPrice   *big.Int         `json:"gasPrice,omitempty"`
+	GasTipCap  *big.Int         `json:"maxPriorityFeePerGas,omitempty"`
+	GasFeeCap  *big.Int         `json:"maxFeePerGas,omitempty"`
+	Data       hexutil.Bytes    `json:"data,omitempty"`
+	AccessList types.AccessList `json:"accessList,omitempty"`
+}
+
+// NewEnvelope 用未签名的交易创建信封，chainID 为交易要签到的链
+func NewEnvelope(chainID *big.Int, from common.Address, tx *types.Transaction) (*Envelope, error) {
+	env := &Envelope{
+		Type:       tx.Type(),
+		ChainID:    new(big.Int).Set(chainID),
+		From:       from,
+		Nonce:      tx.Nonce(),
+		To:         tx.To(),
+		Value:      tx.Value(),
+		Gas:        tx.Gas(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		env.GasPrice = tx.GasPrice()
+	case types.DynamicFeeTxType:
+		env.GasTipCap, env.GasFeeCap = tx.GasTipCap(), tx.GasFeeCap()
+	default:
+		return nil, fmt.Errorf("不支持的交易类型 %d", tx.Type())
+	}
+	return env, nil
+}
+
+// LoadEnvelope 读取并校验信封文件
+func LoadEnvelope(path string) (*Envelope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易信封失败: %w", err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("解析交易信封 %s 失败: %w", path, err)
+	}
+	if _, err := env.Transaction(); err != nil {
+		return nil, fmt.Errorf("交易信封 %s 无效: %w", path, err)
+	}
+	return &env, nil
+}
+
+// Save 将信封写入文件
+func (e *Envelope) Save(path string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("写入交易信封失败: %w", err)
+	}
+	return nil
+}
+
+// Transaction 校验信封并返回对应的未签名交易
+func (e *Envelope) Transaction() (*types.Transaction, error) {
+	if e.ChainID == nil || e.ChainID.Sign() <= 0 {
+		return nil, errors.New("缺少链ID")
+	}
+	value := e.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	if value.Sign() < 0 {
+		return nil, errors.New("金额不能为负数")
+	}
+	if e.Gas == 0 {
+		return nil, errors.New("缺少 Gas 限制")
+	}
+	switch e.Type {
+	case types.LegacyTxType:
+		if e.GasPrice == nil {
+			return nil, errors.New("legacy 交易缺少 gasPrice")
+		}
+		if len(e.AccessList) > 0 {
+			return nil, errors.New("legacy 交易不能带访问列表，请使用类型 1 或 2")
+		}
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    e.Nonce,
+			GasPrice: e.GasPrice,
+			Gas:      e.Gas,
+			To:       e.To,
+			Value:    value,
+			Data:     e.Data,
+		}), nil
+	case types.AccessListTxType:
+		if e.GasPrice == nil {
+			return nil, errors.New("访问列表交易缺少 gasPrice")
+		}
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    e.ChainID,
+			Nonce:      e.Nonce,
+			GasPrice:   e.GasPrice,
+			Gas:        e.Gas,
+			To:         e.To,
+			Value:      value,
+			Data:       e.Data,
+			AccessList: e.AccessList,
+		}), nil
+	case types.DynamicFeeTxType:
+		if e.GasTipCap == nil || e.GasFeeCap == nil {
+			return nil, errors.New("EIP-1559 交易缺少 maxPriorityFeePerGas 或 maxFeePerGas")
+		}
+		if e.GasTipCap.Cmp(e.GasFeeCap) > 0 {
+			return nil, errors.New("maxPriorityFeePerGas 不能高于 maxFeePerGas")
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    e.ChainID,
+			Nonce:      e.Nonce,
+			GasTipCap:  e.GasTipCap,
+			GasFeeCap:  e.GasFeeCap,
+			Gas:        e.Gas,
+			To:         e.To,
+			Value:      value,
+			Data:       e.Data,
+			AccessList: e.AccessList,
+		}), nil
+	default:
+		return nil, fmt.Errorf("不支持的交易类型 %d", e.Type)
+	}
+}
+
+// Signer 返回信封链ID对应的签名规则，legacy 交易按 EIP-155 签名
+func (e *Envelope) Signer() types.Signer {
+	return types.LatestSignerForChainID(e.ChainID)
+}
+
+// EncodeRawTx 将已签名的交易编码为 eth_sendRawTransaction 使用的十六进制字符串
+// （legacy 交易为 RLP 编码，其他类型为类型前缀加 RLP 编码）
+func EncodeRawTx(tx *types.Transaction) (string, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return hexutil.Encode(data), nil
+}
+
+// DecodeRawTx 解析十六进制的已签名交易，允许首尾空白，拒绝未受 EIP-155 保护的交易
+func DecodeRawTx(raw string) (*types.Transaction, error) {
+	data, err := hexutil.Decode(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("无效的十六进制交易: %w", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("解析交易失败: %w", err)
+	}
+	if !tx.Protected() {
+		return nil, errors.New("交易未受 EIP-155 保护，可以在任意链上重放")
+	}
+	return tx, nil
+}
@@ -0,0 +1,65 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"practical-task/units"
+)
+
+// ErrNoBaseFee 表示最新区块没有基础费用，即链尚未启用 EIP-1559
+var ErrNoBaseFee = errors.New("最新区块没有基础费用，链可能未启用 EIP-1559，请使用 legacy 交易")
+
+// ErrFeeCapBelowBaseFee 表示截断后的总费用上限低于当前基础费用，交易在基础费用回落之前无法上链
+var ErrFeeCapBelowBaseFee = errors.New("总费用上限低于当前基础费用，交易在基础费用回落之前无法上链")
+
+// DynamicFees 是 EIP-1559 交易的费用参数
+type DynamicFees struct {
+	BaseFee   *big.Int // 最新区块的基础费用
+	GasTipCap *big.Int // 每单位 Gas 的小费上限（maxPriorityFeePerGas）
+	GasFeeCap *big.Int // 每单位 Gas 的总费用上限（maxFeePerGas）
+}
+
+// SuggestDynamicFees 根据节点建议的小费和最新区块的基础费用计算 EIP-1559 费用参数。
+// 总费用上限取 2 * 基础费用 + 小费，足以承受连续 6 个满块带来的基础费用上涨
+func SuggestDynamicFees(ctx context.Context, client *ethclient.Client) (*DynamicFees, error) {
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, ErrNoBaseFee
+	}
+	tip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+	return &DynamicFees{BaseFee: head.BaseFee, GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// ApplyLimits 应用配置中的费用设置：tipCap 非 nil 时使用固定小费并重新计算总费用上限，
+// maxFeeCap 非 nil 时总费用上限不超过该值（小费上限也随之不超过总费用上限）。
+// 截断后的总费用上限低于基础费用时返回 ErrFeeCapBelowBaseFee，费用仍按截断后的值设置
+func (f *DynamicFees) ApplyLimits(tipCap, maxFeeCap *big.Int) error {
+	if tipCap != nil {
+		f.GasTipCap = new(big.Int).Set(tipCap)
+		f.GasFeeCap = new(big.Int).Mul(f.BaseFee, big.NewInt(2))
+		f.GasFeeCap.Add(f.GasFeeCap, f.GasTipCap)
+	}
+	if maxFeeCap != nil && f.GasFeeCap.Cmp(maxFeeCap) > 0 {
+		f.GasFeeCap = new(big.Int).Set(maxFeeCap)
+	}
+	if f.GasTipCap.Cmp(f.GasFeeCap) > 0 {
+		f.GasTipCap = new(big.Int).Set(f.GasFeeCap)
+	}
+	if f.GasFeeCap.Cmp(f.BaseFee) < 0 {
+		return fmt.Errorf("%w: 总费用上限 %s gwei，基础费用 %s gwei", ErrFeeCapBelowBaseFee,
+			units.FormatGwei(f.GasFeeCap), units.FormatGwei(f.BaseFee))
+	}
+	return nil
+}
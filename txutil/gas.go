@@ -0,0 +1,58 @@
+package txutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultGasMultiplier 是未配置时 Gas 估算值的安全系数
+const DefaultGasMultiplier = 1.2
+
+// GasLimit 是估算得到的 Gas 用量与最终选定的 Gas 限制
+type GasLimit struct {
+	Estimate uint64 // 节点估算的 Gas 用量
+	Limit    uint64 // 乘以安全系数并应用上限后的 Gas 限制
+	Capped   bool   // Gas 限制是否被上限截断
+}
+
+// EstimateGasLimit 估算交易的 Gas 用量，并乘以安全系数得到 Gas 限制。multiplier 不大于 0 时
+// 使用 DefaultGasMultiplier；limitCap 大于 0 时 Gas 限制不超过该值，若估算值本身已超过上限则返回错误，
+// 因为以该上限发送的交易必然耗尽 Gas
+func EstimateGasLimit(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, multiplier float64, limitCap uint64) (*GasLimit, error) {
+	estimate, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if multiplier <= 0 {
+		multiplier = DefaultGasMultiplier
+	}
+	gl := &GasLimit{Estimate: estimate, Limit: estimate}
+	if scaled := math.Ceil(float64(estimate) * multiplier); scaled > float64(estimate) {
+		if scaled >= math.MaxUint64 {
+			gl.Limit = math.MaxUint64
+		} else {
+			gl.Limit = uint64(scaled)
+		}
+	}
+	if limitCap > 0 && gl.Limit > limitCap {
+		if estimate > limitCap {
+			return nil, fmt.Errorf("估算的 Gas 用量 %d 超过上限 %d", estimate, limitCap)
+		}
+		gl.Limit = limitCap
+		gl.Capped = true
+	}
+	return gl, nil
+}
+
+// String 返回估算值与 Gas 限制的说明，用于输出
+func (gl *GasLimit) String() string {
+	s := fmt.Sprintf("估算 %d，Gas限制 %d", gl.Estimate, gl.Limit)
+	if gl.Capped {
+		s += "（已按上限截断）"
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package txutil
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"practical-task/units"
+)
+
+// InsufficientFundsError 表示账户余额不足以支付交易的最坏情况费用
+type InsufficientFundsError struct {
+	Account   common.Address
+	Balance   *big.Int
+	Cost      *big.Int
+	Shortfall *big.Int
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("账户 %s 余额不足: 余额 %s ETH，最坏情况费用 %s ETH，缺少 %s ETH",
+		e.Account.Hex(), units.FormatEther(e.Balance), units.FormatEther(e.Cost), units.FormatEther(e.Shortfall))
+}
+
+// MaxCost 计算交易的最坏情况费用：转账金额 + Gas限制 * 每单位 Gas 的最高价格（EIP-1559 交易为总费用上限，
+// legacy 交易为 gasPrice），blob 交易另加 blob Gas * blob 费用上限。与 types.Transaction.Cost 的
+// 计算方式相同，但可以在创建交易之前使用
+func MaxCost(value *big.Int, gasLimit uint64, gasFeeCap *big.Int, blobGas uint64, blobFeeCap *big.Int) *big.Int {
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasFeeCap)
+	if value != nil {
+		cost.Add(cost, value)
+	}
+	if blobGas > 0 && blobFeeCap != nil {
+		cost.Add(cost, new(big.Int).Mul(new(big.Int).SetUint64(blobGas), blobFeeCap))
+	}
+	return cost
+}
+
+// CheckBalance 查询账户在 pending 区块的余额并与最坏情况费用比较，余额不足时返回
+// *InsufficientFundsError。查询成功时总是返回余额
+func CheckBalance(ctx context.Context, client *ethclient.Client, account common.Address, cost *big.Int) (*big.Int, error) {
+	balance, err := client.PendingBalanceAt(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(cost) < 0 {
+		return balance, &InsufficientFundsError{
+			Account:   account,
+			Balance:   balance,
+			Cost:      cost,
+			Shortfall: new(big.Int).Sub(cost, balance),
+		}
+	}
+	return balance, nil
+}
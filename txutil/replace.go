@@ -0,0 +1,138 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// MinReplacementBump 是节点交易池接受替换交易所需的最低费用涨幅（百分比），
+// 小费上限与总费用上限（legacy 交易为 gasPrice）都必须达到该涨幅
+const MinReplacementBump = 10
+
+// ErrTxNotPending 表示找不到处于待处理状态的交易
+var ErrTxNotPending = errors.New("交易池中没有该 nonce 的待处理交易")
+
+// BumpFee 将费用提高 percent 百分比并向上取整
+func BumpFee(fee *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// FindPendingTx 通过 txpool_contentFrom 在节点交易池中查找 from 发出的指定 nonce 的交易。
+// 节点不支持 txpool 命名空间时返回错误，此时需要由调用方提供交易哈希
+func FindPendingTx(ctx context.Context, client *rpc.Client, from common.Address, nonce uint64) (*types.Transaction, error) {
+	var content map[string]map[string]*types.Transaction
+	if err := client.CallContext(ctx, &content, "txpool_contentFrom", from); err != nil {
+		return nil, fmt.Errorf("查询交易池失败: %w", err)
+	}
+	key := fmt.Sprint(nonce)
+	for _, pool := range []string{"pending", "queued"} {
+		if tx := content[pool][key]; tx != nil {
+			return tx, nil
+		}
+	}
+	return nil, ErrTxNotPending
+}
+
+// ReplacementFees 是替换交易时节点建议的费用，与原交易类型对应的字段必须设置
+type ReplacementFees struct {
+	GasPrice *big.Int     // legacy 与 access list 交易
+	Dynamic  *DynamicFees // EIP-1559 与 EIP-7702 交易
+}
+
+// SpeedUpTx 以相同的 nonce、接收方、金额、数据和授权列表创建提高费用后的替换交易（未签名）
+func SpeedUpTx(old *types.Transaction, chainID *big.Int, fees ReplacementFees, bumpPercent uint64) (*types.Transaction, error) {
+	return replaceTx(old, chainID, old.To(), old.Value(), old.Data(), old.Gas(), old.AccessList(), old.SetCodeAuthorizations(), fees, bumpPercent)
+}
+
+// CancelTx 创建取消交易（未签名）：以相同的 nonce 和提高后的费用向自己转账 0，
+// 上链后原交易因 nonce 已被使用而失效。EIP-7702 交易以不带授权的 EIP-1559 交易取消
+func CancelTx(old *types.Transaction, from common.Address, chainID *big.Int, fees ReplacementFees, bumpPercent uint64) (*types.Transaction, error) {
+	return replaceTx(old, chainID, &from, new(big.Int), nil, params.TxGas, nil, nil, fees, bumpPercent)
+}
+
+// CanReplace 判断是否支持替换该类型的交易：legacy、access list、EIP-1559 与 EIP-7702 交易
+func CanReplace(txType uint8) bool {
+	switch txType {
+	case types.LegacyTxType, types.AccessListTxType, types.DynamicFeeTxType, types.SetCodeTxType:
+		return true
+	}
+	return false
+}
+
+// replaceTx 创建与原交易费用类型相同的替换交易，每项费用取原费用提高 bumpPercent 后与建议费用中的较大者。
+// bumpPercent 小于 MinReplacementBump 时使用 MinReplacementBump；EIP-7702 交易带 auths 时仍为 EIP-7702 交易，
+// 否则为 EIP-1559 交易
+func replaceTx(old *types.Transaction, chainID *big.Int, to *common.Address, value *big.Int, data []byte, gas uint64, accessList types.AccessList, auths []types.SetCodeAuthorization, fees ReplacementFees, bumpPercent uint64) (*types.Transaction, error) {
+	if bumpPercent < MinReplacementBump {
+		bumpPercent = MinReplacementBump
+	}
+	if to == nil {
+		return nil, errors.New("不支持替换合约创建交易")
+	}
+	switch old.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		if fees.GasPrice == nil {
+			return nil, errors.New("缺少建议的 Gas 价格")
+		}
+		gasPrice := maxBig(BumpFee(old.GasPrice(), bumpPercent), fees.GasPrice)
+		if old.Type() == types.LegacyTxType {
+			return types.NewTx(&types.LegacyTx{
+				Nonce:    old.Nonce(),
+				GasPrice: gasPrice,
+				Gas:      gas,
+				To:       to,
+				Value:    value,
+				Data:     data,
+			}), nil
+		}
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      old.Nonce(),
+			GasPrice:   gasPrice,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		}), nil
+	case types.DynamicFeeTxType, types.SetCodeTxType:
+		if fees.Dynamic == nil {
+			return nil, errors.New("缺少建议的 EIP-1559 费用")
+		}
+		tip := maxBig(BumpFee(old.GasTipCap(), bumpPercent), fees.Dynamic.GasTipCap)
+		feeCap := maxBig(BumpFee(old.GasFeeCap(), bumpPercent), fees.Dynamic.GasFeeCap)
+		feeCap = maxBig(feeCap, tip)
+		if old.Type() == types.SetCodeTxType && len(auths) > 0 {
+			return NewSetCodeTx(chainID, old.Nonce(), *to, value, gas, data, accessList, &DynamicFees{GasTipCap: tip, GasFeeCap: feeCap}, auths)
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      old.Nonce(),
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		}), nil
+	default:
+		return nil, fmt.Errorf("不支持替换类型为 %d 的交易", old.Type())
+	}
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return new(big.Int).Set(b)
+}
@@ -0,0 +1,127 @@
+package txutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Solidity 内置错误的选择器
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panicReasons 是 Solidity Panic(uint256) 错误码的含义
+var panicReasons = map[uint64]string{
+	0x00: "通用 panic",
+	0x01: "assert 断言失败",
+	0x11: "算术运算上溢或下溢",
+	0x12: "除以零或对零取模",
+	0x21: "转换为枚举时数值越界",
+	0x22: "访问编码错误的存储字节数组",
+	0x31: "对空数组执行 pop",
+	0x32: "数组或 bytesN 越界访问",
+	0x41: "分配的内存过多或数组过大",
+	0x51: "调用未初始化的内部函数",
+}
+
+// ErrNotReproduced 表示重放失败的交易时执行成功，无法得到失败原因
+var ErrNotReproduced = errors.New("重放交易执行成功，未能复现失败（可能受同一区块中排在前面的交易影响）")
+
+// RevertError 表示交易执行被回滚
+type RevertError struct {
+	Data   []byte // 回滚数据，节点未返回时为空
+	Reason string // 解码后的回滚原因
+}
+
+func (e *RevertError) Error() string {
+	return "执行回滚: " + e.Reason
+}
+
+// DecodeRevert 解码回滚数据：Error(string) 返回原因字符串，Panic(uint256) 返回错误码及其含义，
+// 其他数据按 contract 中定义的自定义错误解码（contract 可以为 nil），无法解码时返回原始数据
+func DecodeRevert(data []byte, contract *abi.ABI) string {
+	if len(data) == 0 {
+		return "无回滚原因"
+	}
+	if len(data) >= 4 {
+		switch {
+		case bytes.Equal(data[:4], errorSelector):
+			if reason, err := abi.UnpackRevert(data); err == nil {
+				return reason
+			}
+		case bytes.Equal(data[:4], panicSelector):
+			if code, ok := unpackPanic(data[4:]); ok {
+				reason := "未知错误码"
+				if r, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+					reason = r
+				}
+				return fmt.Sprintf("Panic(%#x): %s", code, reason)
+			}
+		case contract != nil:
+			if custom, err := contract.ErrorByID([4]byte(data[:4])); err == nil {
+				if values, err := custom.Unpack(data); err == nil {
+					return formatCustomError(custom, values.([]interface{}))
+				}
+			}
+		}
+	}
+	return "未知回滚数据 " + hexutil.Encode(data)
+}
+
+// unpackPanic 解码 Panic(uint256) 的错误码
+func unpackPanic(data []byte) (*big.Int, bool) {
+	if len(data) != 32 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(data), true
+}
+
+// formatCustomError 将自定义错误格式化为 Name(参数名=值, ...)
+func formatCustomError(e *abi.Error, values []interface{}) string {
+	args := make([]string, len(values))
+	for i, v := range values {
+		args[i] = fmt.Sprintf("%s=%v", e.Inputs[i].Name, v)
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+// ReplayFailure 以 eth_call 在交易所在区块的父区块状态上重放已上链的失败交易，返回失败原因：
+// 回滚时为 *RevertError，其他执行错误（如 Gas 耗尽）原样返回，重放成功时为 ErrNotReproduced。
+// 重放不指定费用，避免父区块的基础费用高于交易的费用上限；无法完成重放时返回 err
+func ReplayFailure(ctx context.Context, client *ethclient.Client, from common.Address, tx *types.Transaction, receipt *types.Receipt, contract *abi.ABI) (reason, err error) {
+	if receipt.BlockNumber == nil || receipt.BlockNumber.Sign() == 0 {
+		return nil, errors.New("交易回执缺少区块号")
+	}
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	if tx.Type() == types.SetCodeTxType {
+		msg.AuthorizationList = tx.SetCodeAuthorizations()
+	}
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	if _, err := client.CallContract(ctx, msg, parent); err != nil {
+		if execErr := ExecutionError(err, contract); execErr != nil {
+			return execErr, nil
+		}
+		return nil, err
+	}
+	return ErrNotReproduced, nil
+}
@@ -0,0 +1,103 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"practical-task/task-2/counter"
+)
+
+// revertedError 是节点在执行回滚时返回的 JSON-RPC 错误（错误码 3，data 为回滚数据）
+type revertedError struct{ data []byte }
+
+func (e revertedError) Error() string          { return "execution reverted" }
+func (e revertedError) ErrorCode() int         { return 3 }
+func (e revertedError) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+// revertingEth 模拟所有估算都回滚的节点 eth 命名空间
+type revertingEth struct{ data []byte }
+
+func (s *revertingEth) EstimateGas(ctx context.Context, args map[string]interface{}) (hexutil.Uint64, error) {
+	return 0, revertedError{s.data}
+}
+
+// revertingClient 返回通过进程内 JSON-RPC 连接到 revertingEth 的客户端
+func revertingClient(t *testing.T, data []byte) *ethclient.Client {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &revertingEth{data: data}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestEstimateRevertDecodesCounterReason(t *testing.T) {
+	counterABI, err := counter.CounterMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Counter 的 onlyOwner 修饰符以 Error(string) 回滚
+	str, _ := abi.NewType("string", "", nil)
+	args, err := abi.Arguments{{Type: str}}.Pack("Only owner can call this function")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := revertingClient(t, append(append([]byte{}, errorSelector...), args...))
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	input, _ := counterABI.Pack("reset")
+	_, err = EstimateGasLimit(context.Background(), client, ethereum.CallMsg{To: &to, Data: input}, 0, 0)
+	if err == nil {
+		t.Fatal("EstimateGasLimit succeeded, want revert")
+	}
+	var revert *RevertError
+	if !errors.As(ExecutionError(err, counterABI), &revert) {
+		t.Fatalf("ExecutionError(%v) is not a *RevertError", err)
+	}
+	if revert.Reason != "Only owner can call this function" {
+		t.Fatalf("reason = %q", revert.Reason)
+	}
+}
+
+func TestEstimateRevertDecodesCustomError(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"NotOwner","inputs":[
+		{"name":"caller","type":"address"},{"name":"newCount","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := contract.Errors["NotOwner"]
+	caller := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	args, err := custom.Inputs.Pack(caller, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := revertingClient(t, append(custom.ID[:4:4], args...))
+
+	_, err = EstimateGasLimit(context.Background(), client, ethereum.CallMsg{}, 0, 0)
+	var revert *RevertError
+	if !errors.As(ExecutionError(err, &contract), &revert) {
+		t.Fatalf("ExecutionError(%v) is not a *RevertError", err)
+	}
+	want := "NotOwner(caller=" + caller.Hex() + ", newCount=7)"
+	if revert.Reason != want {
+		t.Fatalf("reason = %q, want %q", revert.Reason, want)
+	}
+}
+
+func TestExecutionErrorIgnoresTransportErrors(t *testing.T) {
+	if err := ExecutionError(errors.New("connection refused"), nil); err != nil {
+		t.Fatalf("ExecutionError(transport error) = %v, want nil", err)
+	}
+}
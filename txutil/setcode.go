@@ -0,0 +1,70 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/holiman/uint256"
+)
+
+// ErrNotEOA 表示地址上部署的是合约代码而不是 EIP-7702 委托标识
+var ErrNotEOA = errors.New("地址上部署了合约代码，不是 EOA")
+
+// Delegation 查询 EOA 当前的 EIP-7702 委托：账户代码为委托标识（0xef0100 + 地址）时返回委托的目标地址，
+// 没有委托时 ok 为 false，地址是合约账户时返回 ErrNotEOA
+func Delegation(ctx context.Context, client *ethclient.Client, account common.Address) (delegate common.Address, ok bool, err error) {
+	code, err := client.CodeAt(ctx, account, nil)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	if len(code) == 0 {
+		return common.Address{}, false, nil
+	}
+	if delegate, ok = types.ParseDelegation(code); !ok {
+		return common.Address{}, false, ErrNotEOA
+	}
+	return delegate, true, nil
+}
+
+// NewAuthorization 创建未签名的 EIP-7702 授权。chainID 为 0 表示授权可以在任意链上使用；
+// delegate 为零地址表示清除账户现有的委托。nonce 必须等于授权账户执行授权时的 nonce，
+// 授权账户同时是交易发送方时为交易 nonce + 1
+func NewAuthorization(chainID *big.Int, delegate common.Address, nonce uint64) (types.SetCodeAuthorization, error) {
+	id, overflow := uint256.FromBig(chainID)
+	if overflow || chainID.Sign() < 0 {
+		return types.SetCodeAuthorization{}, fmt.Errorf("无效的链ID %s", chainID)
+	}
+	return types.SetCodeAuthorization{ChainID: *id, Address: delegate, Nonce: nonce}, nil
+}
+
+// NewSetCodeTx 创建未签名的 EIP-7702 交易（类型 4），auths 为已签名的授权列表
+func NewSetCodeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gas uint64, data []byte, accessList types.AccessList, fees *DynamicFees, auths []types.SetCodeAuthorization) (*types.Transaction, error) {
+	if len(auths) == 0 {
+		return nil, errors.New("EIP-7702 交易至少需要一个授权")
+	}
+	ints := make([]*uint256.Int, 4)
+	for i, v := range []*big.Int{chainID, fees.GasTipCap, fees.GasFeeCap, value} {
+		n, overflow := uint256.FromBig(v)
+		if overflow || v.Sign() < 0 {
+			return nil, fmt.Errorf("数值 %s 超出范围", v)
+		}
+		ints[i] = n
+	}
+	return types.NewTx(&types.SetCodeTx{
+		ChainID:    ints[0],
+		Nonce:      nonce,
+		GasTipCap:  ints[1],
+		GasFeeCap:  ints[2],
+		Gas:        gas,
+		To:         to,
+		Value:      ints[3],
+		Data:       data,
+		AccessList: accessList,
+		AuthList:   auths,
+	}), nil
+}
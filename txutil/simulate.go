@@ -0,0 +1,83 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PendingBlock 是待处理区块的区块号参数
+var PendingBlock = big.NewInt(int64(rpc.PendingBlockNumber))
+
+// Simulation 是模拟执行交易的结果
+type Simulation struct {
+	Return  []byte // 执行成功时的返回数据
+	GasUsed uint64 // 执行成功时 eth_estimateGas 得到的 Gas 用量
+	Err     error  // 执行失败的原因，回滚时为 *RevertError，nil 表示执行成功
+}
+
+// TxCallMsg 返回与交易完全相同的调用参数（发送方、接收方、金额、数据、Gas 限制、费用、访问列表与授权列表），
+// 用于在不签名、不发送的情况下模拟执行该交易
+func TxCallMsg(from common.Address, tx *types.Transaction) ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		msg.GasPrice = tx.GasPrice()
+	default:
+		msg.GasFeeCap, msg.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	}
+	if tx.Type() == types.SetCodeTxType {
+		msg.AuthorizationList = tx.SetCodeAuthorizations()
+	}
+	return msg
+}
+
+// Simulate 在待处理区块上以 eth_call 模拟执行交易，不消耗任何资金。执行失败（回滚、余额不足等）
+// 记录在结果的 Err 中，只有无法完成模拟时才返回错误。contract 用于解码自定义错误，可以为 nil
+func Simulate(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, contract *abi.ABI) (*Simulation, error) {
+	ret, err := client.CallContract(ctx, msg, PendingBlock)
+	if err != nil {
+		if execErr := ExecutionError(err, contract); execErr != nil {
+			return &Simulation{Err: execErr}, nil
+		}
+		return nil, err
+	}
+	gas, err := client.EstimateGasAtBlock(ctx, msg, PendingBlock)
+	if err != nil {
+		return nil, fmt.Errorf("估算Gas用量失败: %w", err)
+	}
+	return &Simulation{Return: ret, GasUsed: gas}, nil
+}
+
+// ExecutionError 从 eth_call、eth_estimateGas 等调用的错误中区分执行失败与网络等其他错误：
+// 节点返回的回滚转换为 *RevertError（按 contract 解码自定义错误，可以为 nil），
+// 其他由节点返回的错误原样返回，网络错误返回 nil
+func ExecutionError(err error, contract *abi.ABI) error {
+	if data, ok := ethclient.RevertErrorData(err); ok {
+		return &RevertError{Data: data, Reason: DecodeRevert(data, contract)}
+	}
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return nil
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return &RevertError{Reason: DecodeRevert(nil, contract)}
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	// ErrReplaced 表示交易的 nonce 已被另一笔不在跟踪范围内的交易使用
+	ErrReplaced = errors.New("交易的 nonce 已被另一笔交易使用，交易已被替换")
+	// ErrDropped 表示交易已不在节点的交易池中，且 nonce 尚未被使用
+	ErrDropped = errors.New("交易已从交易池中消失，可能已被丢弃")
+)
+
+// WaitOptions 是等待交易确认的参数
+type WaitOptions struct {
+	Confirmations uint64        // 需要的确认数，包含交易所在区块，0 视为 1
+	PollInterval  time.Duration // 轮询间隔，0 使用默认值 3 秒
+	DroppedAfter  int           // 连续多少次轮询查不到交易时视为已丢弃，0 使用默认值 10
+
+	// OnProgress 在交易已上链但确认数不足时调用，可为空
+	OnProgress func(tx *types.Transaction, receipt *types.Receipt, confirmations uint64)
+}
+
+// Confirmation 是已达到所需确认数的交易及其回执
+type Confirmation struct {
+	Tx            *types.Transaction
+	Receipt       *types.Receipt
+	Confirmations uint64
+}
+
+// Fee 返回交易实际支付的费用：Gas用量 * 实际Gas价格，blob 交易另加 blob 费用
+func (c *Confirmation) Fee() *big.Int {
+	r := c.Receipt
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice)
+	if r.BlobGasUsed > 0 && r.BlobGasPrice != nil {
+		fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(r.BlobGasUsed), r.BlobGasPrice))
+	}
+	return fee
+}
+
+// WaitConfirmed 等待 from 发出的一组相同 nonce 的交易中的某一笔上链并达到所需确认数，返回上链的那一笔。
+// 只跟踪一笔交易时传入一笔即可；加速或取消交易后传入全部竞争交易。
+// nonce 已被其他交易使用时返回 ErrReplaced，所有交易都从交易池消失时返回 ErrDropped，
+// 超时或取消由 ctx 控制。已上链的交易若因重组离开规范链，会继续等待
+func WaitConfirmed(ctx context.Context, client *ethclient.Client, from common.Address, opts WaitOptions, txs ...*types.Transaction) (*Confirmation, error) {
+	if len(txs) == 0 {
+		return nil, errors.New("没有需要等待的交易")
+	}
+	nonce := txs[0].Nonce()
+	for _, tx := range txs[1:] {
+		if tx.Nonce() != nonce {
+			return nil, fmt.Errorf("交易 %s 的 nonce %d 与 %d 不一致", tx.Hash().Hex(), tx.Nonce(), nonce)
+		}
+	}
+	if opts.Confirmations == 0 {
+		opts.Confirmations = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 3 * time.Second
+	}
+	if opts.DroppedAfter <= 0 {
+		opts.DroppedAfter = 10
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	missing := 0
+	for {
+		done, err := pollConfirmation(ctx, client, from, nonce, &opts, &missing, txs)
+		if done != nil || err != nil {
+			return done, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollConfirmation 检查一次交易状态，尚需继续等待时返回 nil, nil
+func pollConfirmation(ctx context.Context, client *ethclient.Client, from common.Address, nonce uint64, opts *WaitOptions, missing *int, txs []*types.Transaction) (*Confirmation, error) {
+	for _, tx := range txs {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		confirmations := uint64(0)
+		if mined := receipt.BlockNumber.Uint64(); head >= mined {
+			confirmations = head - mined + 1
+		}
+		if confirmations >= opts.Confirmations {
+			return &Confirmation{Tx: tx, Receipt: receipt, Confirmations: confirmations}, nil
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(tx, receipt, confirmations)
+		}
+		*missing = 0
+		return nil, nil
+	}
+
+	// 没有任何一笔上链：检查是否仍在交易池中
+	for _, tx := range txs {
+		_, _, err := client.TransactionByHash(ctx, tx.Hash())
+		if err == nil {
+			*missing = 0
+			return nil, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+	}
+	// 都不在交易池中：nonce 已被使用说明被其他交易替换，否则计为一次丢失
+	confirmed, err := client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	if confirmed > nonce {
+		// 查询回执与 nonce 之间可能有新区块，再次确认跟踪的交易都没有上链
+		for _, tx := range txs {
+			if _, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+				return nil, nil
+			}
+		}
+		return nil, ErrReplaced
+	}
+	*missing++
+	if *missing >= opts.DroppedAfter {
+		return nil, ErrDropped
+	}
+	return nil, nil
+}
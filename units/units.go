@@ -0,0 +1,110 @@
+package units
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// 常用单位相对于最小单位的小数位数
+const (
+	WeiDecimals   = 0
+	GweiDecimals  = 9
+	EtherDecimals = 18
+)
+
+// Decimals 返回以太币单位名对应的小数位数，支持 wei、gwei、ether（eth），不区分大小写
+func Decimals(unit string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "wei":
+		return WeiDecimals, nil
+	case "gwei":
+		return GweiDecimals, nil
+	case "eth", "ether":
+		return EtherDecimals, nil
+	}
+	return 0, fmt.Errorf("不支持的单位 %q，可用单位: wei、gwei、ether", unit)
+}
+
+// Parse 将十进制金额精确换算为最小单位，decimals 为小数位数（ERC-20 代币按其 decimals 传入）。
+// 不经过浮点数；小数位超出精度且不为 0 时返回错误而不是舍入，不接受负数
+func Parse(amount string, decimals int) (*big.Int, error) {
+	if decimals < 0 {
+		return nil, fmt.Errorf("无效的小数位数 %d", decimals)
+	}
+	s := strings.ReplaceAll(strings.TrimSpace(amount), "_", "")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return nil, fmt.Errorf("无效的金额 %q", amount)
+	}
+	if len(frac) > decimals {
+		if strings.Trim(frac[decimals:], "0") != "" {
+			return nil, fmt.Errorf("金额 %q 超出 %d 位小数的精度", amount, decimals)
+		}
+		frac = frac[:decimals]
+	}
+	value, ok := new(big.Int).SetString(whole+frac+strings.Repeat("0", decimals-len(frac)), 10)
+	if !ok {
+		return nil, fmt.Errorf("无效的金额 %q", amount)
+	}
+	return value, nil
+}
+
+// ParseUnit 按以太币单位名将金额换算为 wei
+func ParseUnit(amount, unit string) (*big.Int, error) {
+	decimals, err := Decimals(unit)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(amount, decimals)
+}
+
+// ParseAmount 解析可带单位后缀的金额（如 "0.5 ether"、"20gwei"、"1000 wei"），
+// 未带单位时使用 defaultUnit，返回 wei
+func ParseAmount(s, defaultUnit string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r != '.' && r != '_' && (r < '0' || r > '9')
+	})
+	amount, unit := s, defaultUnit
+	if i >= 0 {
+		amount, unit = strings.TrimSpace(s[:i]), s[i:]
+	}
+	if amount == "" {
+		return nil, fmt.Errorf("无效的金额 %q", s)
+	}
+	return ParseUnit(amount, unit)
+}
+
+// Format 将最小单位的数值格式化为十进制金额，去掉小数部分末尾的 0，结果精确无舍入
+func Format(value *big.Int, decimals int) string {
+	if value == nil {
+		return "-"
+	}
+	digits := new(big.Int).Abs(value).String()
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// FormatEther 将 wei 格式化为 ether 金额
+func FormatEther(wei *big.Int) string {
+	return Format(wei, EtherDecimals)
+}
+
+// FormatGwei 将 wei 格式化为 gwei 金额
+func FormatGwei(wei *big.Int) string {
+	return Format(wei, GweiDecimals)
+}
@@ -0,0 +1,134 @@
+package units
+
+import (
+	"math/big"
+	"testing"
+)
+
+func bigInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid test number " + s)
+	}
+	return n
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string // 空字符串表示应返回错误
+	}{
+		{"1", EtherDecimals, "1000000000000000000"},
+		{"0.5", EtherDecimals, "500000000000000000"},
+		{".5", EtherDecimals, "500000000000000000"},
+		{"5.", EtherDecimals, "5000000000000000000"},
+		{"0.000000000000000001", EtherDecimals, "1"},
+		{"1.0000000000000000000", EtherDecimals, "1000000000000000000"}, // 超出精度的部分全为 0
+		{"0.0000000000000000001", EtherDecimals, ""},                    // 超出 18 位小数的精度
+		{"1.5", WeiDecimals, ""},
+		{"1.0", WeiDecimals, "1"},
+		{"1_000", GweiDecimals, "1000000000000"},
+		{" 2.25 ", 6, "2250000"},
+		{"123456789012345678901234567890", EtherDecimals, "123456789012345678901234567890000000000000000000"},
+		{"-1", EtherDecimals, ""},
+		{"+1", EtherDecimals, ""},
+		{"1e18", WeiDecimals, ""},
+		{"1.2.3", EtherDecimals, ""},
+		{".", EtherDecimals, ""},
+		{"", EtherDecimals, ""},
+		{"1", -1, ""},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.decimals)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Parse(%q, %d) = %s, want error", tt.amount, tt.decimals, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %d) error: %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount, defaultUnit string
+		want                string // 空字符串表示应返回错误
+	}{
+		{"0.5 ether", "wei", "500000000000000000"},
+		{"0.5eth", "wei", "500000000000000000"},
+		{"20gwei", "ether", "20000000000"},
+		{"20 GWEI", "ether", "20000000000"},
+		{"1000 wei", "ether", "1000"},
+		{".5 gwei", "ether", "500000000"},
+		{"1.5", "gwei", "1500000000"},
+		{"1.5", "wei", ""},
+		{"0.1 wei", "ether", ""},
+		{"1 btc", "ether", ""},
+		{"-1 ether", "ether", ""},
+		{"ether", "ether", ""},
+		{"", "ether", ""},
+		{"1", "btc", ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount, tt.defaultUnit)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseAmount(%q, %q) = %s, want error", tt.amount, tt.defaultUnit, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %q) error: %v", tt.amount, tt.defaultUnit, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseAmount(%q, %q) = %s, want %s", tt.amount, tt.defaultUnit, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value    *big.Int
+		decimals int
+		want     string
+	}{
+		{big.NewInt(0), EtherDecimals, "0"},
+		{big.NewInt(1), EtherDecimals, "0.000000000000000001"},
+		{bigInt("500000000000000000"), EtherDecimals, "0.5"},
+		{bigInt("1000000000000000000"), EtherDecimals, "1"},
+		{bigInt("1234500000000000000"), EtherDecimals, "1.2345"},
+		{bigInt("123456789012345678901234567890"), EtherDecimals, "123456789012.34567890123456789"},
+		{big.NewInt(-1500000000), GweiDecimals, "-1.5"},
+		{bigInt("-1"), EtherDecimals, "-0.000000000000000001"},
+		{big.NewInt(-42), WeiDecimals, "-42"},
+		{big.NewInt(42), WeiDecimals, "42"},
+		{nil, EtherDecimals, "-"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.value, tt.decimals); got != tt.want {
+			t.Errorf("Format(%s, %d) = %q, want %q", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	for _, s := range []string{"0", "1", "999999999999999999", "1000000000000000001", "123456789012345678901234567890"} {
+		wei := bigInt(s)
+		got, err := Parse(FormatEther(wei), EtherDecimals)
+		if err != nil {
+			t.Fatalf("Parse(FormatEther(%s)) error: %v", s, err)
+		}
+		if got.Cmp(wei) != 0 {
+			t.Errorf("Parse(FormatEther(%s)) = %s", s, got)
+		}
+	}
+}